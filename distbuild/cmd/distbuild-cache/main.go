@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+var (
+	flagListen     = flag.String("listen", ":8090", "address to listen on")
+	flagRoot       = flag.String("root", "distbuild-cache", "cache directory")
+	flagMaxBytes   = flag.Int64("max-bytes", 0, "maximum total size of cached artifacts, 0 means unlimited")
+	flagMaxEntries = flag.Int("max-entries", 0, "maximum number of cached artifacts, 0 means unlimited")
+
+	flagCA   = flag.String("ca", "", "CA certificate used to verify workers, only workers may upload artifacts if set")
+	flagCert = flag.String("cert", "", "TLS certificate of the cache")
+	flagKey  = flag.String("key", "", "TLS key of the cache")
+)
+
+func main() {
+	flag.Parse()
+
+	l, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = l.Sync() }()
+
+	cache, err := artifact.NewCacheWithPolicy(*flagRoot, artifact.EvictionPolicy{
+		MaxBytes:   *flagMaxBytes,
+		MaxEntries: *flagMaxEntries,
+		OnEvict: func(id build.ID) {
+			l.Info("artifact evicted", zap.Stringer("artifact_id", id))
+		},
+	})
+	if err != nil {
+		l.Fatal("failed to open cache", zap.Error(err))
+	}
+
+	authenticator, err := auth.NewServer(auth.Config{CAFile: *flagCA, CertFile: *flagCert, KeyFile: *flagKey})
+	if err != nil {
+		l.Fatal("failed to load certificates", zap.Error(err))
+	}
+
+	server := &http.Server{
+		Addr:      *flagListen,
+		Handler:   remotecache.NewServerWithAuth(l, cache, authenticator),
+		TLSConfig: authenticator.ServerTLS(),
+	}
+
+	l.Info("remote cache started", zap.String("listen", *flagListen), zap.String("root", *flagRoot))
+
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		l.Fatal("http server stopped", zap.Error(err))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+var (
+	flagListen = flag.String("listen", ":8080", "address to listen on")
+	flagGRPC   = flag.String("grpc-listen", "", "address to serve the gRPC API on, disabled if empty")
+	flagRoot   = flag.String("root", "distbuild-coordinator", "directory for the file cache and the journal")
+
+	flagWorkerTimeout  = flag.Duration("worker-timeout", dist.DefaultWorkerTimeout, "consider worker dead after this long without heartbeats")
+	flagMaxBytes       = flag.Int64("max-bytes", 0, "maximum total size of cached source files, 0 means unlimited")
+	flagMaxEntries     = flag.Int("max-entries", 0, "maximum number of cached source files, 0 means unlimited")
+	flagMaxJobsPerUser = flag.Int("max-jobs-per-user", 0, "maximum number of concurrently running jobs of one user, 0 means unlimited")
+
+	flagCA         = flag.String("ca", "", "CA certificate used to verify workers")
+	flagCert       = flag.String("cert", "", "TLS certificate of the coordinator")
+	flagKey        = flag.String("key", "", "TLS key of the coordinator")
+	flagTokensFile = flag.String("tokens-file", "", "file with client bearer tokens, one per line")
+)
+
+// shutdownTimeout ограничивает ожидание запросов при остановке: потоки /build и /attach живут, пока идёт сборка.
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	flag.Parse()
+
+	l, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = l.Sync() }()
+
+	fileCache, err := filecache.NewWithPolicy(filepath.Join(*flagRoot, "filecache"), artifact.EvictionPolicy{
+		MaxBytes:   *flagMaxBytes,
+		MaxEntries: *flagMaxEntries,
+	})
+	if err != nil {
+		l.Fatal("failed to open file cache", zap.Error(err))
+	}
+
+	authConfig := auth.Config{CAFile: *flagCA, CertFile: *flagCert, KeyFile: *flagKey}
+	if *flagTokensFile != "" {
+		authConfig.Tokens, err = auth.ReadTokens(*flagTokensFile)
+		if err != nil {
+			l.Fatal("failed to read tokens", zap.Error(err))
+		}
+	}
+
+	authenticator, err := auth.NewServer(authConfig)
+	if err != nil {
+		l.Fatal("failed to load certificates", zap.Error(err))
+	}
+
+	coordinator, err := dist.NewCoordinatorWithConfig(l, fileCache, dist.Config{
+		RootDir:        filepath.Join(*flagRoot, "state"),
+		WorkerTimeout:  *flagWorkerTimeout,
+		MaxJobsPerUser: *flagMaxJobsPerUser,
+		Auth:           authConfig,
+	})
+	if err != nil {
+		l.Fatal("failed to start coordinator", zap.Error(err))
+	}
+	defer coordinator.Stop()
+
+	if *flagGRPC != "" {
+		lsn, err := net.Listen("tcp", *flagGRPC)
+		if err != nil {
+			l.Fatal("failed to listen", zap.Error(err))
+		}
+
+		server := grpc.NewServer(authenticator.GRPCServerOptions()...)
+		coordinator.RegisterGRPC(server)
+		defer server.Stop()
+
+		go func() {
+			if err := server.Serve(lsn); err != nil {
+				l.Error("grpc server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	l.Info("coordinator started",
+		zap.String("listen", *flagListen),
+		zap.String("grpc_listen", *flagGRPC),
+		zap.String("root", *flagRoot))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *flagListen, Handler: coordinator, TLSConfig: authenticator.ServerTLS()}
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if !errors.Is(err, http.ErrServerClosed) {
+			l.Fatal("http server stopped", zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+	l.Info("coordinator stopping")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		l.Warn("http server shutdown failed", zap.Error(err))
+	}
+}
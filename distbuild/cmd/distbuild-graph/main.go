@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/gograph"
+)
+
+var (
+	flagDir    = flag.String("C", ".", "module root directory")
+	flagOutput = flag.String("o", "", "output file, stdout by default")
+	flagVet    = flag.Bool("vet", false, "add vet job for every package")
+	flagTest   = flag.Bool("test", false, "add test job for every package with tests")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [packages]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	graph, err := gograph.Generate(context.Background(), gograph.Config{
+		Dir:      *flagDir,
+		Patterns: flag.Args(),
+		Vet:      *flagVet,
+		Test:     *flagTest,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *flagOutput != "" {
+		if out, err = os.Create(*flagOutput); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(graph); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
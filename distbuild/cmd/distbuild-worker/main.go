@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/jobexec"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+var (
+	flagListen         = flag.String("listen", ":8081", "address to listen on")
+	flagID             = flag.String("id", "", "endpoint of this worker reachable from other machines, http(s)://<hostname><listen> by default")
+	flagCoordinator    = flag.String("coordinator", "http://localhost:8080", "coordinator endpoint")
+	flagGRPC           = flag.String("coordinator-grpc", "", "coordinator gRPC address in host:port form, HTTP is used if empty")
+	flagRoot           = flag.String("root", "distbuild-worker", "directory for the file and artifact caches")
+	flagSlots          = flag.Int("slots", 1, "number of jobs to run in parallel")
+	flagSandbox        = flag.Bool("sandbox", false, "run jobs inside a sandbox")
+	flagSandboxNetwork = flag.Bool("sandbox-network", false, "allow network access from the sandbox")
+	flagRemoteCache    = flag.String("remote-cache", "", "remote artifact cache endpoint")
+	flagMaxBytes       = flag.Int64("max-bytes", 0, "maximum total size of cached artifacts, 0 means unlimited")
+	flagMaxEntries     = flag.Int("max-entries", 0, "maximum number of cached artifacts, 0 means unlimited")
+
+	flagCA   = flag.String("ca", "", "CA certificate used to verify the coordinator and other workers")
+	flagCert = flag.String("cert", "", "TLS certificate of the worker, must be issued for its hostname")
+	flagKey  = flag.String("key", "", "TLS key of the worker")
+
+	flagLabels = labelsFlag{}
+)
+
+func init() {
+	flag.Var(&flagLabels, "label", "worker label in key=value form, e.g. go=go1.22.1, may be repeated")
+}
+
+type labelsFlag build.Labels
+
+func (f *labelsFlag) String() string {
+	return build.Labels(*f).String()
+}
+
+func (f *labelsFlag) Set(kv string) error {
+	labels, err := build.ParseLabels([]string{kv})
+	if err != nil {
+		return err
+	}
+
+	maps.Copy(*f, labels)
+	return nil
+}
+
+func main() {
+	// Песочница перезапускает этот бинарь, чтобы подготовить окружение джоба.
+	jobexec.Init()
+
+	flag.Parse()
+
+	l, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = l.Sync() }()
+
+	authConfig := auth.Config{CAFile: *flagCA, CertFile: *flagCert, KeyFile: *flagKey}
+	authenticator, err := auth.NewServer(authConfig)
+	if err != nil {
+		l.Fatal("failed to load certificates", zap.Error(err))
+	}
+
+	workerID := *flagID
+	if workerID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			l.Fatal("failed to get hostname", zap.Error(err))
+		}
+
+		_, port, err := net.SplitHostPort(*flagListen)
+		if err != nil {
+			l.Fatal("invalid listen address", zap.Error(err))
+		}
+
+		scheme := "http://"
+		if authenticator.ServerTLS() != nil {
+			scheme = "https://"
+		}
+		workerID = scheme + net.JoinHostPort(hostname, port)
+	}
+
+	fileCache, err := filecache.New(filepath.Join(*flagRoot, "filecache"))
+	if err != nil {
+		l.Fatal("failed to open file cache", zap.Error(err))
+	}
+
+	artifacts, err := artifact.NewCacheWithPolicy(filepath.Join(*flagRoot, "artifacts"), artifact.EvictionPolicy{
+		MaxBytes:   *flagMaxBytes,
+		MaxEntries: *flagMaxEntries,
+	})
+	if err != nil {
+		l.Fatal("failed to open artifact cache", zap.Error(err))
+	}
+
+	w := worker.NewWithConfig(api.WorkerID(workerID), *flagCoordinator, l, fileCache, artifacts, worker.Config{
+		Slots:          *flagSlots,
+		Sandbox:        *flagSandbox,
+		SandboxNetwork: *flagSandboxNetwork,
+		RemoteCache:    *flagRemoteCache,
+		GRPCEndpoint:   *flagGRPC,
+		Auth:           authConfig,
+		Labels:         build.Labels(flagLabels),
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *flagListen, Handler: w, TLSConfig: authenticator.ServerTLS()}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if !errors.Is(err, http.ErrServerClosed) {
+			l.Fatal("http server stopped", zap.Error(err))
+		}
+	}()
+
+	l.Info("worker started",
+		zap.String("worker_id", workerID),
+		zap.String("coordinator", *flagCoordinator),
+		zap.Stringer("labels", build.Labels(flagLabels)))
+
+	if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		l.Fatal("worker stopped", zap.Error(err))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+var (
+	flagCoordinator = flag.String("coordinator", "http://localhost:8080", "coordinator endpoint")
+	flagGRPC        = flag.String("coordinator-grpc", "", "coordinator gRPC address in host:port form, HTTP is used if empty")
+	flagSource      = flag.String("source", ".", "source directory")
+	flagGraph       = flag.String("graph", "graph.json", "file with build graph in json format")
+	flagUser        = flag.String("user", os.Getenv("USER"), "user name for fair scheduling")
+	flagOutputDir   = flag.String("output-dir", "distbuild-out", "directory for downloaded job outputs")
+	flagVerbose     = flag.Bool("v", false, "write client log to stderr")
+	flagCA          = flag.String("ca", "", "CA certificate used to verify the coordinator")
+	flagToken       = flag.String("token", os.Getenv("DISTBUILD_TOKEN"), "bearer token for the coordinator, $DISTBUILD_TOKEN by default")
+
+	flagOutputs outputsFlag
+)
+
+func init() {
+	flag.Var(&flagOutputs, "output", "name of a job whose output should be downloaded, may be repeated")
+}
+
+type outputsFlag []string
+
+func (f *outputsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *outputsFlag) Set(name string) error {
+	*f = append(*f, name)
+	return nil
+}
+
+// printer выводит вывод джобов по мере поступления, предваряя каждую порцию именем джоба.
+type printer struct {
+	names map[build.ID]string
+
+	mu     sync.Mutex
+	failed int
+}
+
+var (
+	_ client.CacheListener         = (*printer)(nil)
+	_ client.FailureReasonListener = (*printer)(nil)
+)
+
+func (p *printer) OnJobStdout(jobID build.ID, stdout []byte) error {
+	_, err := fmt.Fprintf(os.Stdout, "[%s] %s", p.names[jobID], stdout)
+	return err
+}
+
+func (p *printer) OnJobStderr(jobID build.ID, stderr []byte) error {
+	_, err := fmt.Fprintf(os.Stderr, "[%s] %s", p.names[jobID], stderr)
+	return err
+}
+
+func (p *printer) OnJobFinished(jobID build.ID) error {
+	_, err := fmt.Fprintf(os.Stderr, "[%s] done\n", p.names[jobID])
+	return err
+}
+
+func (p *printer) OnJobCached(jobID build.ID) error {
+	_, err := fmt.Fprintf(os.Stderr, "[%s] cached\n", p.names[jobID])
+	return err
+}
+
+func (p *printer) OnJobFailed(jobID build.ID, code int, msg string) error {
+	return p.OnJobFailedWithReason(jobID, code, "", msg)
+}
+
+func (p *printer) OnJobFailedWithReason(jobID build.ID, code int, reason api.FailureReason, msg string) error {
+	p.mu.Lock()
+	p.failed++
+	p.mu.Unlock()
+
+	if reason != "" {
+		msg = fmt.Sprintf("%s: %s", reason, msg)
+	}
+
+	_, err := fmt.Fprintf(os.Stderr, "[%s] failed with exit code %d: %s\n", p.names[jobID], code, msg)
+	return err
+}
+
+func main() {
+	flag.Parse()
+
+	l := zap.NewNop()
+	if *flagVerbose {
+		var err error
+		if l, err = zap.NewDevelopment(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	defer func() { _ = l.Sync() }()
+
+	graphJS, err := os.ReadFile(*flagGraph)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var graph build.Graph
+	if err := json.Unmarshal(graphJS, &graph); err != nil {
+		log.Fatalf("invalid graph %s: %v", *flagGraph, err)
+	}
+
+	lsn := &printer{names: map[build.ID]string{}}
+	byName := map[string]build.ID{}
+	for _, job := range graph.Jobs {
+		lsn.names[job.ID] = job.Name
+		byName[job.Name] = job.ID
+	}
+
+	var outputs []build.ID
+	for _, name := range flagOutputs {
+		id, ok := byName[name]
+		if !ok {
+			log.Fatalf("job %q not found in %s", name, *flagGraph)
+		}
+		outputs = append(outputs, id)
+	}
+
+	// Отмена контекста по Ctrl+C останавливает сборку на координаторе.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.NewClientWithConfig(l, *flagCoordinator, *flagSource, client.Config{
+		User:         *flagUser,
+		GRPCEndpoint: *flagGRPC,
+		Auth:         auth.Config{CAFile: *flagCA, Token: *flagToken},
+	})
+	if err := c.BuildWithOutputs(ctx, graph, outputs, *flagOutputDir, lsn); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, name := range flagOutputs {
+		fmt.Fprintf(os.Stderr, "[%s] output saved to %s\n", name, filepath.Join(*flagOutputDir, byName[name].String()))
+	}
+
+	if lsn.failed != 0 {
+		log.Fatalf("%d jobs failed", lsn.failed)
+	}
+}
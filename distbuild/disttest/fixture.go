@@ -15,12 +15,17 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth/authtest"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/client"
 	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
 	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
 	"gitlab.com/slon/shad-go/tools/testtool"
 
@@ -39,6 +44,13 @@ type env struct {
 	WorkerCache []*artifact.Cache
 
 	HTTP *http.Server
+
+	// Endpoint содержит адрес HTTP сервера, на котором работают координатор и воркеры.
+	Endpoint string
+
+	// CA выписывает сертификаты, если в Config включён Auth. Удалённый кеш с аутентификацией
+	// в этом случае отвечает по адресу Endpoint + "/cache".
+	CA *authtest.CA
 }
 
 const (
@@ -47,8 +59,23 @@ const (
 
 type Config struct {
 	WorkerCount int
+
+	// GRPC переключает клиента и воркеров на gRPC транспорт.
+	GRPC bool
+
+	// Auth включает TLS и проверку запросов. Клиент аутентифицируется токеном testToken,
+	// а координатор и воркеры - сертификатами, выписанными одноразовым CA.
+	Auth bool
+
+	// WorkerLabels задаёт worker.Config.Labels для воркеров с соответствующими номерами.
+	WorkerLabels []build.Labels
+
+	// LabelsGrace задаёт dist.Config.LabelsGrace.
+	LabelsGrace time.Duration
 }
 
+const testToken = "test-token"
+
 func newEnv(t *testing.T, config *Config) (e *env) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -103,29 +130,65 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	port, err := testtool.GetFreePort()
 	require.NoError(t, err)
 	addr := "127.0.0.1:" + port
-	coordinatorEndpoint := "http://" + addr + "/coordinator"
+
+	scheme := "http://"
+	var ca *authtest.CA
+	var coordinatorAuth, clientAuth auth.Config
+	if config.Auth {
+		scheme = "https://"
+		ca = authtest.NewCA(t)
+		env.CA = ca
+
+		coordinatorAuth = ca.Issue(t, "127.0.0.1")
+		coordinatorAuth.Tokens = []string{testToken}
+		clientAuth = auth.Config{CAFile: ca.CAFile, Token: testToken}
+	}
+
+	env.Endpoint = scheme + addr
+	coordinatorEndpoint := env.Endpoint + "/coordinator"
 
 	var cancelRootContext func()
 	env.Ctx, cancelRootContext = context.WithCancel(context.Background())
 	t.Cleanup(cancelRootContext)
 
-	env.Client = client.NewClient(
+	var grpcEndpoint string
+	if config.GRPC {
+		grpcPort, err := testtool.GetFreePort()
+		require.NoError(t, err)
+		grpcEndpoint = "127.0.0.1:" + grpcPort
+	}
+
+	env.Client = client.NewClientWithConfig(
 		env.Logger.Named("client"),
 		coordinatorEndpoint,
-		filepath.Join(absCWD, "testdata", t.Name()))
+		filepath.Join(absCWD, "testdata", t.Name()),
+		client.Config{GRPCEndpoint: grpcEndpoint, Auth: clientAuth})
 
 	coordinatorCache, err := filecache.New(filepath.Join(env.RootDir, "coordinator", "filecache"))
 	require.NoError(t, err)
 
-	env.Coordinator = dist.NewCoordinator(
+	env.Coordinator, err = dist.NewCoordinatorWithConfig(
 		env.Logger.Named("coordinator"),
 		coordinatorCache,
+		dist.Config{Auth: coordinatorAuth, LabelsGrace: config.LabelsGrace},
 	)
+	require.NoError(t, err)
 	t.Cleanup(env.Coordinator.Stop)
 
 	router := http.NewServeMux()
 	router.Handle("/coordinator/", http.StripPrefix("/coordinator", env.Coordinator))
 
+	if config.Auth {
+		remoteCache, err := artifact.NewCache(filepath.Join(env.RootDir, "remotecache"))
+		require.NoError(t, err)
+
+		cacheAuthenticator, err := auth.NewServer(ca.Issue(t, "127.0.0.1"))
+		require.NoError(t, err)
+
+		router.Handle("/cache/", http.StripPrefix("/cache",
+			remotecache.NewServerWithAuth(env.Logger.Named("remotecache"), remoteCache, cacheAuthenticator)))
+	}
+
 	for i := range config.WorkerCount {
 		workerName := fmt.Sprintf("worker%d", i)
 		workerDir := filepath.Join(env.RootDir, workerName)
@@ -139,14 +202,25 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		require.NoError(t, err)
 
 		workerPrefix := fmt.Sprintf("/worker/%d", i)
-		workerID := api.WorkerID("http://" + addr + workerPrefix)
+		workerID := api.WorkerID(env.Endpoint + workerPrefix)
+
+		var workerAuth auth.Config
+		if config.Auth {
+			workerAuth = ca.Issue(t, "127.0.0.1")
+		}
+
+		var workerLabels build.Labels
+		if i < len(config.WorkerLabels) {
+			workerLabels = config.WorkerLabels[i]
+		}
 
-		w := worker.New(
+		w := worker.NewWithConfig(
 			workerID,
 			coordinatorEndpoint,
 			env.Logger.Named(workerName),
 			fileCache,
 			artifacts,
+			worker.Config{GRPCEndpoint: grpcEndpoint, Auth: workerAuth, Labels: workerLabels},
 		)
 
 		env.Workers = append(env.Workers, w)
@@ -155,16 +229,25 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		router.Handle(workerPrefix+"/", http.StripPrefix(workerPrefix, w))
 	}
 
+	coordinatorAuthenticator, err := auth.NewServer(coordinatorAuth)
+	require.NoError(t, err)
+
 	env.HTTP = &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:      addr,
+		Handler:   router,
+		TLSConfig: coordinatorAuthenticator.ServerTLS(),
 	}
 
 	lsn, err := net.Listen("tcp", env.HTTP.Addr)
 	require.NoError(t, err)
 
 	go func() {
-		err := env.HTTP.Serve(lsn)
+		var err error
+		if env.HTTP.TLSConfig != nil {
+			err = env.HTTP.ServeTLS(lsn, "", "")
+		} else {
+			err = env.HTTP.Serve(lsn)
+		}
 		if err != http.ErrServerClosed {
 			env.Logger.Fatal("http server stopped", zap.Error(err))
 		}
@@ -175,6 +258,17 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		_ = env.HTTP.Shutdown(context.Background())
 	})
 
+	if config.GRPC {
+		grpcServer := grpc.NewServer(coordinatorAuthenticator.GRPCServerOptions()...)
+		env.Coordinator.RegisterGRPC(grpcServer)
+
+		grpcLsn, err := net.Listen("tcp", grpcEndpoint)
+		require.NoError(t, err)
+
+		go func() { _ = grpcServer.Serve(grpcLsn) }()
+		t.Cleanup(grpcServer.Stop)
+	}
+
 	for _, w := range env.Workers {
 		go func(w *worker.Worker) {
 			err := w.Run(env.Ctx)
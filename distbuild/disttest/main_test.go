@@ -0,0 +1,14 @@
+package disttest
+
+import (
+	"os"
+	"testing"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/jobexec"
+)
+
+// TestMain нужен воркерам, которые запускают джобы с ограничениями через jobexec.Runner.
+func TestMain(m *testing.M) {
+	jobexec.Init()
+	os.Exit(m.Run())
+}
@@ -1,6 +1,7 @@
 package disttest
 
 import (
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
@@ -8,8 +9,11 @@ type JobResult struct {
 	Stdout string
 	Stderr string
 
-	Code  *int
-	Error string
+	Code   *int
+	Reason api.FailureReason
+	Error  string
+
+	Cached bool
 }
 
 type Recorder struct {
@@ -49,9 +53,20 @@ func (r *Recorder) OnJobFinished(jobID build.ID) error {
 	return nil
 }
 
+func (r *Recorder) OnJobCached(jobID build.ID) error {
+	j := r.job(jobID)
+	j.Cached = true
+	return nil
+}
+
 func (r *Recorder) OnJobFailed(jobID build.ID, code int, error string) error {
+	return r.OnJobFailedWithReason(jobID, code, "", error)
+}
+
+func (r *Recorder) OnJobFailedWithReason(jobID build.ID, code int, reason api.FailureReason, error string) error {
 	j := r.job(jobID)
 	j.Code = &code
+	j.Reason = reason
 	j.Error = error
 	return nil
 }
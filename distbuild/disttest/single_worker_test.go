@@ -1,15 +1,22 @@
 package disttest
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/events"
 )
 
 var singleWorkerConfig = &Config{WorkerCount: 1}
@@ -36,6 +43,16 @@ func TestSingleCommand(t *testing.T) {
 	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
 }
 
+func TestSingleCommandGRPC(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, GRPC: true})
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, recorder))
+
+	assert.Len(t, recorder.Jobs, 1)
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
+
 func TestJobCaching(t *testing.T) {
 	env := newEnv(t, singleWorkerConfig)
 
@@ -71,6 +88,170 @@ func TestJobCaching(t *testing.T) {
 	require.Equal(t, []byte("NOTOK\n"), output)
 }
 
+func TestCachedJobOutput(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "echo",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", "echo OK; echo warning >&2"}},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Stderr: "warning\n", Code: new(int), Cached: true}, recorder.Jobs[build.ID{'a'}])
+}
+
+var cyclicGraph = build.Graph{
+	Jobs: []build.Job{
+		{
+			ID:   build.ID{'a'},
+			Name: "first",
+			Cmds: []build.Cmd{{Exec: []string{"echo", "OK"}}},
+			Deps: []build.ID{{'b'}},
+		},
+		{
+			ID:   build.ID{'b'},
+			Name: "second",
+			Cmds: []build.Cmd{{Exec: []string{"echo", "OK"}}},
+			Deps: []build.ID{{'a'}},
+		},
+	},
+}
+
+func TestInvalidGraph(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	recorder := NewRecorder()
+	err := env.Client.Build(env.Ctx, cyclicGraph, recorder)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"first" -> "second" -> "first"`)
+
+	assert.Empty(t, recorder.Jobs)
+}
+
+func TestMetrics(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+
+	for path, metric := range map[string]string{
+		"/coordinator/metrics": "distbuild_scheduler_picked_jobs_total",
+		"/worker/0/metrics":    "distbuild_worker_job_duration_seconds",
+	} {
+		rsp, err := http.Get("http://" + env.HTTP.Addr + path)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		assert.Contains(t, string(body), metric)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+
+	get := func(query string) []byte {
+		rsp, err := http.Get("http://" + env.HTTP.Addr + "/coordinator/events" + query)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+
+		require.Equal(t, http.StatusOK, rsp.StatusCode, string(body))
+		return body
+	}
+
+	var builds []build.ID
+	require.NoError(t, json.Unmarshal(get(""), &builds))
+	require.Len(t, builds, 1)
+
+	var kinds []events.Kind
+	for _, line := range strings.Split(strings.TrimSpace(string(get("?build_id="+builds[0].String()))), "\n") {
+		var e events.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		require.Equal(t, build.ID{'a'}, e.JobID)
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []events.Kind{events.JobScheduled, events.JobPicked, events.JobStarted, events.JobFinished}, kinds)
+
+	var trace struct {
+		TraceEvents []json.RawMessage `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(get("?format=chrome&build_id="+builds[0].String()), &trace))
+	assert.NotEmpty(t, trace.TraceEvents)
+}
+
+func TestAuth(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, Auth: true})
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+
+	do := func(method, path, token string) int {
+		var body io.Reader
+		if path == "/coordinator/heartbeat" {
+			body = strings.NewReader(fmt.Sprintf(`{"WorkerID": %q}`, env.Endpoint+"/worker/0"))
+		}
+
+		req, err := http.NewRequest(method, env.Endpoint+path, body)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rsp, err := anonymous.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+		return rsp.StatusCode
+	}
+
+	id := build.ID{'a'}.String()
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/coordinator/build"},
+		{http.MethodPost, "/coordinator/heartbeat"},
+		{http.MethodGet, "/coordinator/file?id=" + id},
+		{http.MethodGet, "/coordinator/artifact?id=" + id},
+		{http.MethodGet, "/worker/0/artifact?id=" + id},
+		{http.MethodGet, "/cache/artifact?id=" + id},
+		{http.MethodPut, "/cache/artifact?id=" + id},
+	} {
+		assert.Equal(t, http.StatusUnauthorized, do(req.method, req.path, ""), req.path)
+		assert.Equal(t, http.StatusUnauthorized, do(req.method, req.path, "guess"), req.path)
+	}
+
+	// Клиент с токеном может скачать результат, но не может притвориться воркером.
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/coordinator/artifact?id="+id, testToken))
+	assert.NotEqual(t, http.StatusOK, do(http.MethodPost, "/coordinator/heartbeat", testToken))
+
+	// В удалённый кеш заливать артефакты может только воркер.
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, "/cache/artifact?id="+id, testToken))
+
+	worker, err := auth.New(env.CA.Issue(t, "127.0.0.1"))
+	require.NoError(t, err)
+	require.NoError(t, artifact.UploadWithHTTP(env.Ctx, worker.HTTPClient(), env.Endpoint+"/cache", env.WorkerCache[0], build.ID{'a'}))
+}
+
 var sourceFilesGraph = build.Graph{
 	SourceFiles: map[build.ID]string{
 		{'a'}: "a.txt",
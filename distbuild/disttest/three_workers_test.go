@@ -2,9 +2,14 @@ package disttest
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -62,3 +67,103 @@ func TestArtifactTransferBetweenWorkers(t *testing.T) {
 		defer unlock()
 	}
 }
+
+// waitForWorkers ждёт, пока координатор получит первый heartbeat от каждого воркера.
+func waitForWorkers(t *testing.T, env *env) {
+	require.Eventually(t, func() bool {
+		rsp, err := http.Get("http://" + env.HTTP.Addr + "/coordinator/metrics")
+		if err != nil {
+			return false
+		}
+		defer func() { _ = rsp.Body.Close() }()
+
+		body, err := io.ReadAll(rsp.Body)
+		if err != nil {
+			return false
+		}
+
+		for i := range env.Workers {
+			if !strings.Contains(string(body), fmt.Sprintf(`worker="%s/worker/%d"`, env.Endpoint, i)) {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestWorkerLabels(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount:  3,
+		WorkerLabels: []build.Labels{nil, {"gpu": "nvidia"}, nil},
+		LabelsGrace:  -1,
+	})
+	waitForWorkers(t, env)
+
+	var graph build.Graph
+	for i := range 3 {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:       build.ID{'a', byte(i)},
+			Name:     fmt.Sprintf("train %d", i),
+			Cmds:     []build.Cmd{{Exec: []string{"echo", "OK"}}},
+			Requires: build.Labels{"gpu": "nvidia", build.LabelOS: runtime.GOOS},
+		})
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	require.Len(t, recorder.Jobs, 3)
+
+	for _, job := range graph.Jobs {
+		for i, cache := range env.WorkerCache {
+			_, unlock, err := cache.Get(job.ID)
+			if i == 1 {
+				require.NoError(t, err, "job %s must run on the gpu worker", job.Name)
+				unlock()
+			} else {
+				require.Error(t, err, "job %s must not run on worker %d", job.Name, i)
+			}
+		}
+	}
+
+	unsatisfiable := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:       build.ID{'b'},
+				Name:     "train on tpu",
+				Cmds:     []build.Cmd{{Exec: []string{"echo", "OK"}}},
+				Requires: build.Labels{"tpu": "v5"},
+			},
+		},
+	}
+
+	recorder = NewRecorder()
+	err := env.Client.Build(env.Ctx, unsatisfiable, recorder)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no live worker")
+	require.Empty(t, recorder.Jobs)
+}
+
+func TestWorkerLabelsGrace(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 3,
+		LabelsGrace: 500 * time.Millisecond,
+	})
+
+	unsatisfiable := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:       build.ID{'b'},
+				Name:     "train on tpu",
+				Cmds:     []build.Cmd{{Exec: []string{"echo", "OK"}}},
+				Requires: build.Labels{"tpu": "v5"},
+			},
+		},
+	}
+
+	// Сборка, пришедшая до истечения LabelsGrace, падает, когда координатор проверяет её заново.
+	recorder := NewRecorder()
+	err := env.Client.Build(env.Ctx, unsatisfiable, recorder)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no live worker")
+	require.Empty(t, recorder.Jobs)
+}
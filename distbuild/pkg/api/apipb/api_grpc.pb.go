@@ -0,0 +1,328 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.19.6
+// source: apipb/api.proto
+
+package apipb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Build_StartBuild_FullMethodName  = "/distbuild.api.Build/StartBuild"
+	Build_SignalBuild_FullMethodName = "/distbuild.api.Build/SignalBuild"
+	Build_AttachBuild_FullMethodName = "/distbuild.api.Build/AttachBuild"
+)
+
+// BuildClient is the client API for Build service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BuildClient interface {
+	StartBuild(ctx context.Context, in *BuildRequest, opts ...grpc.CallOption) (Build_StartBuildClient, error)
+	SignalBuild(ctx context.Context, in *SignalBuildRequest, opts ...grpc.CallOption) (*SignalResponse, error)
+	AttachBuild(ctx context.Context, in *AttachBuildRequest, opts ...grpc.CallOption) (Build_AttachBuildClient, error)
+}
+
+type buildClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBuildClient(cc grpc.ClientConnInterface) BuildClient {
+	return &buildClient{cc}
+}
+
+func (c *buildClient) StartBuild(ctx context.Context, in *BuildRequest, opts ...grpc.CallOption) (Build_StartBuildClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Build_ServiceDesc.Streams[0], Build_StartBuild_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &buildStartBuildClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Build_StartBuildClient interface {
+	Recv() (*BuildStatus, error)
+	grpc.ClientStream
+}
+
+type buildStartBuildClient struct {
+	grpc.ClientStream
+}
+
+func (x *buildStartBuildClient) Recv() (*BuildStatus, error) {
+	m := new(BuildStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *buildClient) SignalBuild(ctx context.Context, in *SignalBuildRequest, opts ...grpc.CallOption) (*SignalResponse, error) {
+	out := new(SignalResponse)
+	err := c.cc.Invoke(ctx, Build_SignalBuild_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *buildClient) AttachBuild(ctx context.Context, in *AttachBuildRequest, opts ...grpc.CallOption) (Build_AttachBuildClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Build_ServiceDesc.Streams[1], Build_AttachBuild_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &buildAttachBuildClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Build_AttachBuildClient interface {
+	Recv() (*BuildStatus, error)
+	grpc.ClientStream
+}
+
+type buildAttachBuildClient struct {
+	grpc.ClientStream
+}
+
+func (x *buildAttachBuildClient) Recv() (*BuildStatus, error) {
+	m := new(BuildStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BuildServer is the server API for Build service.
+// All implementations must embed UnimplementedBuildServer
+// for forward compatibility
+type BuildServer interface {
+	StartBuild(*BuildRequest, Build_StartBuildServer) error
+	SignalBuild(context.Context, *SignalBuildRequest) (*SignalResponse, error)
+	AttachBuild(*AttachBuildRequest, Build_AttachBuildServer) error
+	mustEmbedUnimplementedBuildServer()
+}
+
+// UnimplementedBuildServer must be embedded to have forward compatible implementations.
+type UnimplementedBuildServer struct {
+}
+
+func (UnimplementedBuildServer) StartBuild(*BuildRequest, Build_StartBuildServer) error {
+	return status.Errorf(codes.Unimplemented, "method StartBuild not implemented")
+}
+func (UnimplementedBuildServer) SignalBuild(context.Context, *SignalBuildRequest) (*SignalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignalBuild not implemented")
+}
+func (UnimplementedBuildServer) AttachBuild(*AttachBuildRequest, Build_AttachBuildServer) error {
+	return status.Errorf(codes.Unimplemented, "method AttachBuild not implemented")
+}
+func (UnimplementedBuildServer) mustEmbedUnimplementedBuildServer() {}
+
+// UnsafeBuildServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BuildServer will
+// result in compilation errors.
+type UnsafeBuildServer interface {
+	mustEmbedUnimplementedBuildServer()
+}
+
+func RegisterBuildServer(s grpc.ServiceRegistrar, srv BuildServer) {
+	s.RegisterService(&Build_ServiceDesc, srv)
+}
+
+func _Build_StartBuild_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BuildRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BuildServer).StartBuild(m, &buildStartBuildServer{stream})
+}
+
+type Build_StartBuildServer interface {
+	Send(*BuildStatus) error
+	grpc.ServerStream
+}
+
+type buildStartBuildServer struct {
+	grpc.ServerStream
+}
+
+func (x *buildStartBuildServer) Send(m *BuildStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Build_SignalBuild_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignalBuildRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BuildServer).SignalBuild(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Build_SignalBuild_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BuildServer).SignalBuild(ctx, req.(*SignalBuildRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Build_AttachBuild_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AttachBuildRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BuildServer).AttachBuild(m, &buildAttachBuildServer{stream})
+}
+
+type Build_AttachBuildServer interface {
+	Send(*BuildStatus) error
+	grpc.ServerStream
+}
+
+type buildAttachBuildServer struct {
+	grpc.ServerStream
+}
+
+func (x *buildAttachBuildServer) Send(m *BuildStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Build_ServiceDesc is the grpc.ServiceDesc for Build service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Build_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "distbuild.api.Build",
+	HandlerType: (*BuildServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SignalBuild",
+			Handler:    _Build_SignalBuild_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StartBuild",
+			Handler:       _Build_StartBuild_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AttachBuild",
+			Handler:       _Build_AttachBuild_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "apipb/api.proto",
+}
+
+const (
+	Heartbeat_Heartbeat_FullMethodName = "/distbuild.api.Heartbeat/Heartbeat"
+)
+
+// HeartbeatClient is the client API for Heartbeat service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HeartbeatClient interface {
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+}
+
+type heartbeatClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHeartbeatClient(cc grpc.ClientConnInterface) HeartbeatClient {
+	return &heartbeatClient{cc}
+}
+
+func (c *heartbeatClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, Heartbeat_Heartbeat_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HeartbeatServer is the server API for Heartbeat service.
+// All implementations must embed UnimplementedHeartbeatServer
+// for forward compatibility
+type HeartbeatServer interface {
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	mustEmbedUnimplementedHeartbeatServer()
+}
+
+// UnimplementedHeartbeatServer must be embedded to have forward compatible implementations.
+type UnimplementedHeartbeatServer struct {
+}
+
+func (UnimplementedHeartbeatServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedHeartbeatServer) mustEmbedUnimplementedHeartbeatServer() {}
+
+// UnsafeHeartbeatServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HeartbeatServer will
+// result in compilation errors.
+type UnsafeHeartbeatServer interface {
+	mustEmbedUnimplementedHeartbeatServer()
+}
+
+func RegisterHeartbeatServer(s grpc.ServiceRegistrar, srv HeartbeatServer) {
+	s.RegisterService(&Heartbeat_ServiceDesc, srv)
+}
+
+func _Heartbeat_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HeartbeatServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Heartbeat_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HeartbeatServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Heartbeat_ServiceDesc is the grpc.ServiceDesc for Heartbeat service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Heartbeat_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "distbuild.api.Heartbeat",
+	HandlerType: (*HeartbeatServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Heartbeat_Heartbeat_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "apipb/api.proto",
+}
@@ -8,6 +8,12 @@ import (
 
 type BuildRequest struct {
 	Graph build.Graph
+
+	// User задаёт пользователя, запустившего сборку.
+	//
+	// Шедулер делит воркеров поровну между пользователями и может ограничить число джобов
+	// одного пользователя, выполняющихся одновременно. Сборки с пустым User считаются сборками одного пользователя.
+	User string
 }
 
 type BuildStarted struct {
@@ -16,6 +22,7 @@ type BuildStarted struct {
 }
 
 type StatusUpdate struct {
+	JobOutput     *JobOutput
 	JobFinished   *JobResult
 	BuildFailed   *BuildFailed
 	BuildFinished *BuildFinished
@@ -30,8 +37,15 @@ type BuildFinished struct {
 
 type UploadDone struct{}
 
+// CancelBuild останавливает сборку.
+//
+// Координатор убирает из шедулера все джобы сборки, которые ещё не начали выполняться, и просит воркеров
+// остановить те джобы, которые больше не нужны ни одной другой сборке. Сборка завершается с BuildFailed.
+type CancelBuild struct{}
+
 type SignalRequest struct {
-	UploadDone *UploadDone
+	UploadDone  *UploadDone
+	CancelBuild *CancelBuild
 }
 
 type SignalResponse struct {
@@ -45,6 +59,15 @@ type StatusWriter interface {
 type Service interface {
 	StartBuild(ctx context.Context, request *BuildRequest, w StatusWriter) error
 	SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error)
+
+	// AttachBuild подключается к уже запущенной сборке.
+	//
+	// Сервис пишет в w сначала BuildStarted, затем все StatusUpdate, которые сборка уже породила,
+	// а после этого продолжает писать новые обновления, пока сборка не завершится.
+	//
+	// После перезапуска координатора история собирается заново из журнала и содержит только JobFinished
+	// уже завершённых джобов, без JobOutput.
+	AttachBuild(ctx context.Context, buildID build.ID, w StatusWriter) error
 }
 
 type StatusReader interface {
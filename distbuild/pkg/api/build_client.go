@@ -4,6 +4,7 @@ package api
 
 import (
 	"context"
+	"net/http"
 
 	"go.uber.org/zap"
 
@@ -17,6 +18,13 @@ func NewBuildClient(l *zap.Logger, endpoint string) *BuildClient {
 	panic("implement me")
 }
 
+// NewBuildClientWithHTTP работает так же, как NewBuildClient, но посылает запросы через httpClient.
+//
+// Так клиент может предъявить координатору токен и сертификат из auth.Authenticator.HTTPClient.
+func NewBuildClientWithHTTP(l *zap.Logger, endpoint string, httpClient *http.Client) *BuildClient {
+	panic("implement me")
+}
+
 func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
 	panic("implement me")
 }
@@ -24,3 +32,7 @@ func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*B
 func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
 	panic("implement me")
 }
+
+func (c *BuildClient) AttachBuild(ctx context.Context, buildID build.ID) (*BuildStarted, StatusReader, error) {
+	panic("implement me")
+}
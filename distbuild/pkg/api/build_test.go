@@ -158,3 +158,50 @@ func TestBuildResultsStreaming(t *testing.T) {
 	defer r.Close()
 	require.Equal(t, started, rsp)
 }
+
+func TestBuildAttach(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	buildID := build.ID{02}
+	started := &api.BuildStarted{ID: buildID}
+	jobFinished := &api.StatusUpdate{JobFinished: &api.JobResult{ID: build.ID{'a'}}}
+	finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
+
+	env.mock.EXPECT().AttachBuild(gomock.Any(), buildID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ build.ID, w api.StatusWriter) error {
+			if err := w.Started(started); err != nil {
+				return err
+			}
+
+			if err := w.Updated(jobFinished); err != nil {
+				return err
+			}
+
+			return w.Updated(finished)
+		})
+	env.mock.EXPECT().AttachBuild(gomock.Any(), build.ID{03}, gomock.Any()).Return(fmt.Errorf("build not found"))
+
+	rsp, r, err := env.client.AttachBuild(ctx, buildID)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.Equal(t, started, rsp)
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, jobFinished, u)
+
+	u, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, finished, u)
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, _, err = env.client.AttachBuild(ctx, build.ID{03})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "build not found")
+}
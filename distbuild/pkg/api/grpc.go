@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// BuildCaller описывает клиентскую сторону Service.
+//
+// Ему удовлетворяют *BuildClient и *GRPCBuildClient.
+type BuildCaller interface {
+	StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error)
+	SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error)
+	AttachBuild(ctx context.Context, buildID build.ID) (*BuildStarted, StatusReader, error)
+}
+
+var (
+	_ BuildCaller      = (*BuildClient)(nil)
+	_ BuildCaller      = (*GRPCBuildClient)(nil)
+	_ HeartbeatService = (*HeartbeatClient)(nil)
+	_ HeartbeatService = (*GRPCHeartbeatClient)(nil)
+)
+
+// DialGRPC открывает соединение с gRPC сервером координатора по адресу endpoint вида host:port.
+//
+// Соединение устанавливается лениво, при первом вызове. По умолчанию соединение не использует TLS,
+// opts, например из auth.Authenticator.GRPCDialOptions, могут это переопределить.
+func DialGRPC(endpoint string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	return grpc.Dial(endpoint, opts...)
+}
+
+// GRPCBuildClient реализует клиентскую сторону Service поверх gRPC.
+type GRPCBuildClient struct {
+	l      *zap.Logger
+	client apipb.BuildClient
+}
+
+func NewGRPCBuildClient(l *zap.Logger, conn grpc.ClientConnInterface) *GRPCBuildClient {
+	return &GRPCBuildClient{l: l, client: apipb.NewBuildClient(conn)}
+}
+
+// statusStream - общий интерфейс потоков StartBuild и AttachBuild.
+type statusStream interface {
+	Recv() (*apipb.BuildStatus, error)
+}
+
+type grpcStatusReader struct {
+	stream statusStream
+	cancel context.CancelFunc
+}
+
+func (r *grpcStatusReader) Next() (*StatusUpdate, error) {
+	msg, err := r.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	var d decoder
+	update := d.statusUpdate(msg)
+	return update, d.err
+}
+
+func (r *grpcStatusReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+func (c *GRPCBuildClient) readStarted(stream statusStream, cancel context.CancelFunc) (*BuildStarted, StatusReader, error) {
+	msg, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	var d decoder
+	started := d.buildStarted(msg)
+	if d.err != nil {
+		cancel()
+		return nil, nil, d.err
+	}
+
+	return started, &grpcStatusReader{stream: stream, cancel: cancel}, nil
+}
+
+func (c *GRPCBuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
+	c.l.Debug("starting build", zap.Int("num_jobs", len(request.Graph.Jobs)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.client.StartBuild(ctx, buildRequestToProto(request))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return c.readStarted(stream, cancel)
+}
+
+func (c *GRPCBuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
+	c.l.Debug("signaling build", zap.Stringer("build_id", buildID))
+
+	_, err := c.client.SignalBuild(ctx, &apipb.SignalBuildRequest{
+		BuildId: idToProto(buildID),
+		Signal:  signalToProto(signal),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignalResponse{}, nil
+}
+
+func (c *GRPCBuildClient) AttachBuild(ctx context.Context, buildID build.ID) (*BuildStarted, StatusReader, error) {
+	c.l.Debug("attaching to build", zap.Stringer("build_id", buildID))
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.client.AttachBuild(ctx, &apipb.AttachBuildRequest{BuildId: idToProto(buildID)})
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return c.readStarted(stream, cancel)
+}
+
+// GRPCHeartbeatClient реализует HeartbeatService поверх gRPC.
+type GRPCHeartbeatClient struct {
+	l      *zap.Logger
+	client apipb.HeartbeatClient
+}
+
+func NewGRPCHeartbeatClient(l *zap.Logger, conn grpc.ClientConnInterface) *GRPCHeartbeatClient {
+	return &GRPCHeartbeatClient{l: l, client: apipb.NewHeartbeatClient(conn)}
+}
+
+func (c *GRPCHeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	rsp, err := c.client.Heartbeat(ctx, heartbeatRequestToProto(req))
+	if err != nil {
+		c.l.Warn("heartbeat failed", zap.Error(err))
+		return nil, err
+	}
+
+	var d decoder
+	out := d.heartbeatResponse(rsp)
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid heartbeat response: %w", d.err)
+	}
+	return out, nil
+}
+
+// GRPCBuildHandler отдаёт Service по gRPC.
+type GRPCBuildHandler struct {
+	apipb.UnimplementedBuildServer
+
+	l *zap.Logger
+	s Service
+}
+
+func NewGRPCBuildHandler(l *zap.Logger, s Service) *GRPCBuildHandler {
+	return &GRPCBuildHandler{l: l, s: s}
+}
+
+func (h *GRPCBuildHandler) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterBuildServer(s, h)
+}
+
+type grpcStatusWriter struct {
+	send    func(*apipb.BuildStatus) error
+	started bool
+}
+
+func (w *grpcStatusWriter) Started(rsp *BuildStarted) error {
+	if w.started {
+		return fmt.Errorf("build already started")
+	}
+
+	w.started = true
+	return w.send(buildStartedToProto(rsp))
+}
+
+func (w *grpcStatusWriter) Updated(update *StatusUpdate) error {
+	if !w.started {
+		return fmt.Errorf("build is not started")
+	}
+
+	return w.send(statusUpdateToProto(update))
+}
+
+// finish превращает ошибку сервиса в ответ.
+//
+// Пока BuildStarted не отправлен, ошибка возвращается как ошибка вызова. После этого ошибка
+// передаётся в потоке как StatusUpdate.BuildFailed.
+func (w *grpcStatusWriter) finish(err error) error {
+	if err == nil || !w.started {
+		return err
+	}
+
+	return w.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: err.Error()}})
+}
+
+func (h *GRPCBuildHandler) StartBuild(req *apipb.BuildRequest, stream apipb.Build_StartBuildServer) error {
+	var d decoder
+	request := d.buildRequest(req)
+	if d.err != nil {
+		return status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("build requested", zap.Int("num_jobs", len(request.Graph.Jobs)))
+
+	w := &grpcStatusWriter{send: stream.Send}
+	err := h.s.StartBuild(stream.Context(), request, w)
+	if err != nil {
+		h.l.Warn("build failed", zap.Error(err))
+	}
+	return w.finish(err)
+}
+
+func (h *GRPCBuildHandler) SignalBuild(ctx context.Context, req *apipb.SignalBuildRequest) (*apipb.SignalResponse, error) {
+	var d decoder
+	buildID := d.id(req.GetBuildId())
+	signal := d.signal(req.GetSignal())
+	if d.err != nil {
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("build signaled", zap.Stringer("build_id", buildID))
+
+	if _, err := h.s.SignalBuild(ctx, buildID, signal); err != nil {
+		h.l.Warn("signal failed", zap.Stringer("build_id", buildID), zap.Error(err))
+		return nil, err
+	}
+
+	return &apipb.SignalResponse{}, nil
+}
+
+func (h *GRPCBuildHandler) AttachBuild(req *apipb.AttachBuildRequest, stream apipb.Build_AttachBuildServer) error {
+	var d decoder
+	buildID := d.id(req.GetBuildId())
+	if d.err != nil {
+		return status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("attach requested", zap.Stringer("build_id", buildID))
+
+	w := &grpcStatusWriter{send: stream.Send}
+	err := h.s.AttachBuild(stream.Context(), buildID, w)
+	if err != nil {
+		h.l.Warn("attach failed", zap.Stringer("build_id", buildID), zap.Error(err))
+	}
+	return w.finish(err)
+}
+
+// GRPCHeartbeatHandler отдаёт HeartbeatService по gRPC.
+type GRPCHeartbeatHandler struct {
+	apipb.UnimplementedHeartbeatServer
+
+	l *zap.Logger
+	s HeartbeatService
+}
+
+func NewGRPCHeartbeatHandler(l *zap.Logger, s HeartbeatService) *GRPCHeartbeatHandler {
+	return &GRPCHeartbeatHandler{l: l, s: s}
+}
+
+func (h *GRPCHeartbeatHandler) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterHeartbeatServer(s, h)
+}
+
+func (h *GRPCHeartbeatHandler) Heartbeat(ctx context.Context, req *apipb.HeartbeatRequest) (*apipb.HeartbeatResponse, error) {
+	var d decoder
+	request := d.heartbeatRequest(req)
+	if d.err != nil {
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	rsp, err := h.s.Heartbeat(ctx, request)
+	if err != nil {
+		h.l.Warn("heartbeat failed", zap.Stringer("worker_id", request.WorkerID), zap.Error(err))
+		return nil, err
+	}
+
+	return heartbeatResponseToProto(rsp), nil
+}
@@ -0,0 +1,487 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Функции этого файла переводят типы пакета api в сообщения apipb и обратно.
+//
+// Пустые слайсы и map-ы превращаются в nil, чтобы значение, прошедшее через gRPC, совпадало
+// со значением, прошедшим через json.
+
+func idToProto(id build.ID) []byte {
+	return id[:]
+}
+
+func idsToProto(ids []build.ID) [][]byte {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	out := make([][]byte, len(ids))
+	for i, id := range ids {
+		out[i] = idToProto(id)
+	}
+	return out
+}
+
+func fileMapToProto(files map[build.ID]string) map[string]string {
+	if len(files) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(files))
+	for id, path := range files {
+		out[id.String()] = path
+	}
+	return out
+}
+
+func copiesToProto(copies map[string]build.ID) map[string]string {
+	if len(copies) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(copies))
+	for path, id := range copies {
+		out[path] = id.String()
+	}
+	return out
+}
+
+func labelsToProto(labels build.Labels) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func jobToProto(job *build.Job) *apipb.Job {
+	out := &apipb.Job{
+		Id:     idToProto(job.ID),
+		Name:   job.Name,
+		Inputs: job.Inputs,
+		Deps:   idsToProto(job.Deps),
+		Limits: &apipb.Limits{
+			TimeoutNanos: int64(job.Limits.Timeout),
+			Memory:       job.Limits.Memory,
+			CpuTimeNanos: int64(job.Limits.CPUTime),
+		},
+		Requires: labelsToProto(job.Requires),
+	}
+
+	for _, cmd := range job.Cmds {
+		out.Cmds = append(out.Cmds, &apipb.Cmd{
+			Exec:             cmd.Exec,
+			Environ:          cmd.Environ,
+			WorkingDirectory: cmd.WorkingDirectory,
+			CatTemplate:      cmd.CatTemplate,
+			CatOutput:        cmd.CatOutput,
+		})
+	}
+
+	return out
+}
+
+func buildRequestToProto(req *BuildRequest) *apipb.BuildRequest {
+	graph := &apipb.Graph{
+		SourceFiles:  fileMapToProto(req.Graph.SourceFiles),
+		SourceCopies: copiesToProto(req.Graph.SourceCopies),
+	}
+	for i := range req.Graph.Jobs {
+		graph.Jobs = append(graph.Jobs, jobToProto(&req.Graph.Jobs[i]))
+	}
+
+	return &apipb.BuildRequest{Graph: graph, User: req.User}
+}
+
+func timeToProto(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func jobResultToProto(res *JobResult) *apipb.JobResult {
+	out := &apipb.JobResult{
+		Id:                  idToProto(res.ID),
+		Stdout:              res.Stdout,
+		Stderr:              res.Stderr,
+		ExitCode:            int64(res.ExitCode),
+		Error:               res.Error,
+		Reason:              string(res.Reason),
+		StartedAtUnixNanos:  timeToProto(res.StartedAt),
+		FinishedAtUnixNanos: timeToProto(res.FinishedAt),
+		Cached:              res.Cached,
+	}
+
+	for _, download := range res.Downloads {
+		out.Downloads = append(out.Downloads, &apipb.ArtifactDownload{
+			Id:                 idToProto(download.ID),
+			From:               string(download.From),
+			Size:               download.Size,
+			StartedAtUnixNanos: timeToProto(download.StartedAt),
+			DurationNanos:      int64(download.Duration),
+		})
+	}
+
+	return out
+}
+
+func jobOutputToProto(out *JobOutput) *apipb.JobOutput {
+	return &apipb.JobOutput{
+		Id:      idToProto(out.ID),
+		Stdout:  out.Stdout,
+		Stderr:  out.Stderr,
+		Attempt: int64(out.Attempt),
+	}
+}
+
+func buildStartedToProto(started *BuildStarted) *apipb.BuildStatus {
+	return &apipb.BuildStatus{
+		Status: &apipb.BuildStatus_Started{Started: &apipb.BuildStarted{
+			Id:           idToProto(started.ID),
+			MissingFiles: idsToProto(started.MissingFiles),
+		}},
+	}
+}
+
+func statusUpdateToProto(update *StatusUpdate) *apipb.BuildStatus {
+	out := &apipb.StatusUpdate{}
+	if update.JobOutput != nil {
+		out.JobOutput = jobOutputToProto(update.JobOutput)
+	}
+	if update.JobFinished != nil {
+		out.JobFinished = jobResultToProto(update.JobFinished)
+	}
+	if update.BuildFailed != nil {
+		out.BuildFailed = &apipb.BuildFailed{Error: update.BuildFailed.Error}
+	}
+	if update.BuildFinished != nil {
+		out.BuildFinished = &apipb.BuildFinished{}
+	}
+
+	return &apipb.BuildStatus{Status: &apipb.BuildStatus_Update{Update: out}}
+}
+
+func signalToProto(signal *SignalRequest) *apipb.SignalRequest {
+	out := &apipb.SignalRequest{}
+	if signal.UploadDone != nil {
+		out.UploadDone = &apipb.UploadDone{}
+	}
+	if signal.CancelBuild != nil {
+		out.CancelBuild = &apipb.CancelBuild{}
+	}
+	return out
+}
+
+func heartbeatRequestToProto(req *HeartbeatRequest) *apipb.HeartbeatRequest {
+	out := &apipb.HeartbeatRequest{
+		WorkerId:         string(req.WorkerID),
+		RunningJobs:      idsToProto(req.RunningJobs),
+		FreeSlots:        int64(req.FreeSlots),
+		AddedArtifacts:   idsToProto(req.AddedArtifacts),
+		RemovedArtifacts: idsToProto(req.RemovedArtifacts),
+		Labels:           labelsToProto(req.Labels),
+	}
+
+	for i := range req.FinishedJob {
+		out.FinishedJob = append(out.FinishedJob, jobResultToProto(&req.FinishedJob[i]))
+	}
+	for i := range req.JobOutput {
+		out.JobOutput = append(out.JobOutput, jobOutputToProto(&req.JobOutput[i]))
+	}
+
+	return out
+}
+
+func heartbeatResponseToProto(rsp *HeartbeatResponse) *apipb.HeartbeatResponse {
+	out := &apipb.HeartbeatResponse{
+		JobsToCancel:       idsToProto(rsp.JobsToCancel),
+		ReportAllArtifacts: rsp.ReportAllArtifacts,
+	}
+
+	if len(rsp.JobsToRun) != 0 {
+		out.JobsToRun = make(map[string]*apipb.JobSpec, len(rsp.JobsToRun))
+	}
+
+	for id, spec := range rsp.JobsToRun {
+		artifacts := make(map[string]string, len(spec.Artifacts))
+		for artifactID, workerID := range spec.Artifacts {
+			artifacts[artifactID.String()] = string(workerID)
+		}
+		if len(artifacts) == 0 {
+			artifacts = nil
+		}
+
+		out.JobsToRun[id.String()] = &apipb.JobSpec{
+			SourceFiles:  fileMapToProto(spec.SourceFiles),
+			SourceCopies: copiesToProto(spec.SourceCopies),
+			Artifacts:    artifacts,
+			Job:          jobToProto(&spec.Job),
+		}
+	}
+
+	return out
+}
+
+// decoder переводит сообщения apipb в типы пакета api и запоминает первую ошибку.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) id(b []byte) build.ID {
+	var id build.ID
+	if len(b) != len(id) {
+		d.fail(fmt.Errorf("invalid id size: %d", len(b)))
+		return id
+	}
+
+	copy(id[:], b)
+	return id
+}
+
+func (d *decoder) hexID(s string) build.ID {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(s)); err != nil {
+		d.fail(err)
+	}
+	return id
+}
+
+func (d *decoder) ids(bs [][]byte) []build.ID {
+	if len(bs) == 0 {
+		return nil
+	}
+
+	out := make([]build.ID, len(bs))
+	for i, b := range bs {
+		out[i] = d.id(b)
+	}
+	return out
+}
+
+func (d *decoder) fileMap(files map[string]string) map[build.ID]string {
+	if len(files) == 0 {
+		return nil
+	}
+
+	out := make(map[build.ID]string, len(files))
+	for id, path := range files {
+		out[d.hexID(id)] = path
+	}
+	return out
+}
+
+func (d *decoder) copies(copies map[string]string) map[string]build.ID {
+	if len(copies) == 0 {
+		return nil
+	}
+
+	out := make(map[string]build.ID, len(copies))
+	for path, id := range copies {
+		out[path] = d.hexID(id)
+	}
+	return out
+}
+
+func (d *decoder) labels(labels map[string]string) build.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func (d *decoder) job(job *apipb.Job) build.Job {
+	out := build.Job{
+		ID:     d.id(job.GetId()),
+		Name:   job.GetName(),
+		Inputs: job.GetInputs(),
+		Deps:   d.ids(job.GetDeps()),
+		Limits: build.Limits{
+			Timeout: time.Duration(job.GetLimits().GetTimeoutNanos()),
+			Memory:  job.GetLimits().GetMemory(),
+			CPUTime: time.Duration(job.GetLimits().GetCpuTimeNanos()),
+		},
+		Requires: d.labels(job.GetRequires()),
+	}
+
+	for _, cmd := range job.GetCmds() {
+		out.Cmds = append(out.Cmds, build.Cmd{
+			Exec:             cmd.GetExec(),
+			Environ:          cmd.GetEnviron(),
+			WorkingDirectory: cmd.GetWorkingDirectory(),
+			CatTemplate:      cmd.GetCatTemplate(),
+			CatOutput:        cmd.GetCatOutput(),
+		})
+	}
+
+	return out
+}
+
+func (d *decoder) buildRequest(req *apipb.BuildRequest) *BuildRequest {
+	out := &BuildRequest{
+		Graph: build.Graph{
+			SourceFiles:  d.fileMap(req.GetGraph().GetSourceFiles()),
+			SourceCopies: d.copies(req.GetGraph().GetSourceCopies()),
+		},
+		User: req.GetUser(),
+	}
+
+	for _, job := range req.GetGraph().GetJobs() {
+		out.Graph.Jobs = append(out.Graph.Jobs, d.job(job))
+	}
+
+	return out
+}
+
+func timeFromProto(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func (d *decoder) jobResult(res *apipb.JobResult) JobResult {
+	out := JobResult{
+		ID:         d.id(res.GetId()),
+		Stdout:     res.GetStdout(),
+		Stderr:     res.GetStderr(),
+		ExitCode:   int(res.GetExitCode()),
+		Error:      res.Error,
+		Reason:     FailureReason(res.GetReason()),
+		StartedAt:  timeFromProto(res.GetStartedAtUnixNanos()),
+		FinishedAt: timeFromProto(res.GetFinishedAtUnixNanos()),
+		Cached:     res.GetCached(),
+	}
+
+	for _, download := range res.GetDownloads() {
+		out.Downloads = append(out.Downloads, ArtifactDownload{
+			ID:        d.id(download.GetId()),
+			From:      WorkerID(download.GetFrom()),
+			Size:      download.GetSize(),
+			StartedAt: timeFromProto(download.GetStartedAtUnixNanos()),
+			Duration:  time.Duration(download.GetDurationNanos()),
+		})
+	}
+
+	return out
+}
+
+func (d *decoder) jobOutput(out *apipb.JobOutput) JobOutput {
+	return JobOutput{
+		ID:      d.id(out.GetId()),
+		Stdout:  out.GetStdout(),
+		Stderr:  out.GetStderr(),
+		Attempt: int(out.GetAttempt()),
+	}
+}
+
+func (d *decoder) buildStarted(status *apipb.BuildStatus) *BuildStarted {
+	started := status.GetStarted()
+	if started == nil {
+		d.fail(fmt.Errorf("expected BuildStarted, got %v", status))
+		return nil
+	}
+
+	return &BuildStarted{
+		ID:           d.id(started.GetId()),
+		MissingFiles: d.ids(started.GetMissingFiles()),
+	}
+}
+
+func (d *decoder) statusUpdate(status *apipb.BuildStatus) *StatusUpdate {
+	update := status.GetUpdate()
+	if update == nil {
+		d.fail(fmt.Errorf("expected StatusUpdate, got %v", status))
+		return nil
+	}
+
+	out := &StatusUpdate{}
+	if update.JobOutput != nil {
+		jobOutput := d.jobOutput(update.JobOutput)
+		out.JobOutput = &jobOutput
+	}
+	if update.JobFinished != nil {
+		jobResult := d.jobResult(update.JobFinished)
+		out.JobFinished = &jobResult
+	}
+	if update.BuildFailed != nil {
+		out.BuildFailed = &BuildFailed{Error: update.BuildFailed.GetError()}
+	}
+	if update.BuildFinished != nil {
+		out.BuildFinished = &BuildFinished{}
+	}
+	return out
+}
+
+func (d *decoder) signal(signal *apipb.SignalRequest) *SignalRequest {
+	out := &SignalRequest{}
+	if signal.GetUploadDone() != nil {
+		out.UploadDone = &UploadDone{}
+	}
+	if signal.GetCancelBuild() != nil {
+		out.CancelBuild = &CancelBuild{}
+	}
+	return out
+}
+
+func (d *decoder) heartbeatRequest(req *apipb.HeartbeatRequest) *HeartbeatRequest {
+	out := &HeartbeatRequest{
+		WorkerID:         WorkerID(req.GetWorkerId()),
+		RunningJobs:      d.ids(req.GetRunningJobs()),
+		FreeSlots:        int(req.GetFreeSlots()),
+		AddedArtifacts:   d.ids(req.GetAddedArtifacts()),
+		RemovedArtifacts: d.ids(req.GetRemovedArtifacts()),
+		Labels:           d.labels(req.GetLabels()),
+	}
+
+	for _, res := range req.GetFinishedJob() {
+		out.FinishedJob = append(out.FinishedJob, d.jobResult(res))
+	}
+	for _, output := range req.GetJobOutput() {
+		out.JobOutput = append(out.JobOutput, d.jobOutput(output))
+	}
+
+	return out
+}
+
+func (d *decoder) heartbeatResponse(rsp *apipb.HeartbeatResponse) *HeartbeatResponse {
+	out := &HeartbeatResponse{
+		JobsToCancel:       d.ids(rsp.GetJobsToCancel()),
+		ReportAllArtifacts: rsp.GetReportAllArtifacts(),
+	}
+
+	if len(rsp.GetJobsToRun()) != 0 {
+		out.JobsToRun = make(map[build.ID]JobSpec, len(rsp.GetJobsToRun()))
+	}
+
+	for id, spec := range rsp.GetJobsToRun() {
+		var artifacts map[build.ID]WorkerID
+		for artifactID, workerID := range spec.GetArtifacts() {
+			if artifacts == nil {
+				artifacts = make(map[build.ID]WorkerID, len(spec.GetArtifacts()))
+			}
+			artifacts[d.hexID(artifactID)] = WorkerID(workerID)
+		}
+
+		out.JobsToRun[d.hexID(id)] = JobSpec{
+			SourceFiles:  d.fileMap(spec.GetSourceFiles()),
+			SourceCopies: d.copies(spec.GetSourceCopies()),
+			Artifacts:    artifacts,
+			Job:          d.job(spec.GetJob()),
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,270 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/mock"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+type grpcEnv struct {
+	build     *mock.MockService
+	heartbeat *mock.MockHeartbeatService
+
+	client          *api.GRPCBuildClient
+	heartbeatClient *api.GRPCHeartbeatClient
+}
+
+func newGRPCEnv(t *testing.T) *grpcEnv {
+	ctrl := gomock.NewController(t)
+	log := zaptest.NewLogger(t)
+
+	env := &grpcEnv{
+		build:     mock.NewMockService(ctrl),
+		heartbeat: mock.NewMockHeartbeatService(ctrl),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	api.NewGRPCBuildHandler(log, env.build).Register(server)
+	api.NewGRPCHeartbeatHandler(log, env.heartbeat).Register(server)
+
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	env.client = api.NewGRPCBuildClient(log, conn)
+	env.heartbeatClient = api.NewGRPCHeartbeatClient(log, conn)
+	return env
+}
+
+func TestGRPCBuildRunning(t *testing.T) {
+	env := newGRPCEnv(t)
+
+	req := &api.BuildRequest{
+		Graph: build.Graph{
+			SourceFiles:  map[build.ID]string{{01}: "a.txt"},
+			SourceCopies: map[string]build.ID{"b.txt": {01}},
+			Jobs: []build.Job{
+				{
+					ID:     build.ID{03},
+					Name:   "cat",
+					Inputs: []string{"a.txt"},
+					Deps:   []build.ID{{04}},
+					Cmds: []build.Cmd{
+						{Exec: []string{"cat", "a.txt"}, Environ: []string{"A=B"}, WorkingDirectory: "{{.SourceDir}}"},
+						{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/ok"},
+					},
+					Limits: build.Limits{Timeout: time.Minute, Memory: 1 << 30, CPUTime: time.Second},
+				},
+			},
+		},
+		User: "alice",
+	}
+
+	started := &api.BuildStarted{ID: build.ID{02}, MissingFiles: []build.ID{{01}}}
+	failed := "exit status 1"
+	jobFinished := &api.StatusUpdate{JobFinished: &api.JobResult{
+		ID:       build.ID{03},
+		Stdout:   []byte("out"),
+		ExitCode: 1,
+		Error:    &failed,
+		Reason:   api.FailureTimeout,
+	}}
+	finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
+
+	env.build.EXPECT().StartBuild(gomock.Any(), gomock.Eq(req), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *api.BuildRequest, w api.StatusWriter) error {
+			if err := w.Started(started); err != nil {
+				return err
+			}
+
+			if err := w.Updated(jobFinished); err != nil {
+				return err
+			}
+
+			if err := w.Updated(finished); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("foo bar error")
+		})
+
+	rsp, r, err := env.client.StartBuild(context.Background(), req)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.Equal(t, started, rsp)
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, jobFinished, u)
+
+	u, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, finished, u)
+
+	u, err = r.Next()
+	require.NoError(t, err)
+	require.Contains(t, u.BuildFailed.Error, "foo bar error")
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestGRPCBuildStartError(t *testing.T) {
+	env := newGRPCEnv(t)
+
+	env.build.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("foo bar error"))
+
+	_, _, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "foo bar error")
+}
+
+func TestGRPCBuildResultsStreaming(t *testing.T) {
+	env := newGRPCEnv(t)
+
+	started := &api.BuildStarted{ID: build.ID{02}}
+	canceled := make(chan struct{})
+
+	env.build.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ *api.BuildRequest, w api.StatusWriter) error {
+			if err := w.Started(started); err != nil {
+				return err
+			}
+
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		})
+
+	rsp, r, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+	require.NoError(t, err)
+	require.Equal(t, started, rsp)
+
+	require.NoError(t, r.Close())
+	<-canceled
+}
+
+func TestGRPCBuildSignalAndAttach(t *testing.T) {
+	env := newGRPCEnv(t)
+	ctx := context.Background()
+
+	buildID := build.ID{02}
+	signal := &api.SignalRequest{CancelBuild: &api.CancelBuild{}}
+
+	env.build.EXPECT().SignalBuild(gomock.Any(), buildID, gomock.Eq(signal)).Return(&api.SignalResponse{}, nil)
+	env.build.EXPECT().SignalBuild(gomock.Any(), build.ID{03}, gomock.Any()).Return(nil, fmt.Errorf("build not found"))
+
+	_, err := env.client.SignalBuild(ctx, buildID, signal)
+	require.NoError(t, err)
+
+	_, err = env.client.SignalBuild(ctx, build.ID{03}, signal)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "build not found")
+
+	started := &api.BuildStarted{ID: buildID}
+	output := &api.StatusUpdate{JobOutput: &api.JobOutput{ID: build.ID{'a'}, Stderr: []byte("warning\n"), Attempt: 1}}
+
+	env.build.EXPECT().AttachBuild(gomock.Any(), buildID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ build.ID, w api.StatusWriter) error {
+			if err := w.Started(started); err != nil {
+				return err
+			}
+
+			return w.Updated(output)
+		})
+	env.build.EXPECT().AttachBuild(gomock.Any(), build.ID{03}, gomock.Any()).Return(fmt.Errorf("build not found"))
+
+	rsp, r, err := env.client.AttachBuild(ctx, buildID)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.Equal(t, started, rsp)
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, output, u)
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, _, err = env.client.AttachBuild(ctx, build.ID{03})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "build not found")
+}
+
+func TestGRPCHeartbeat(t *testing.T) {
+	env := newGRPCEnv(t)
+
+	canceled := "job canceled"
+	req := &api.HeartbeatRequest{
+		WorkerID:    "worker0",
+		RunningJobs: []build.ID{{0x03}},
+		FreeSlots:   2,
+		Labels:      build.Labels{build.LabelArch: "arm64", "go": "go1.22.1"},
+		FinishedJob: []api.JobResult{
+			{ID: build.ID{0x02}, ExitCode: -1, Error: &canceled, Reason: api.FailureCanceled},
+			{
+				ID: build.ID{0x08},
+				Downloads: []api.ArtifactDownload{
+					{ID: build.ID{0x09}, From: "worker1", Size: 1024, StartedAt: time.Unix(100, 0), Duration: time.Second},
+				},
+				StartedAt:  time.Unix(101, 0),
+				FinishedAt: time.Unix(102, 500),
+			},
+			{ID: build.ID{0x0a}, Cached: true},
+		},
+		JobOutput: []api.JobOutput{
+			{ID: build.ID{0x03}, Stdout: []byte("compiling...\n")},
+		},
+		AddedArtifacts:   []build.ID{{0x04}},
+		RemovedArtifacts: []build.ID{{0x05}},
+	}
+	rsp := &api.HeartbeatResponse{
+		JobsToRun: map[build.ID]api.JobSpec{
+			{0x01}: {
+				SourceFiles:  map[build.ID]string{{0x06}: "a.c"},
+				SourceCopies: map[string]build.ID{"b.c": {0x06}},
+				Artifacts:    map[build.ID]api.WorkerID{{0x07}: "worker1"},
+				Job:          build.Job{ID: build.ID{0x01}, Name: "cc a.c"},
+			},
+		},
+		JobsToCancel:       []build.ID{{0x02}},
+		ReportAllArtifacts: true,
+	}
+
+	gomock.InOrder(
+		env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Return(rsp, nil),
+		env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Return(nil, fmt.Errorf("build error: foo bar")),
+	)
+
+	clientRsp, err := env.heartbeatClient.Heartbeat(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, rsp, clientRsp)
+
+	_, err = env.heartbeatClient.Heartbeat(context.Background(), req)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "foo bar")
+}
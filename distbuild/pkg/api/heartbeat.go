@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
@@ -10,6 +11,9 @@ import (
 type JobResult struct {
 	ID build.ID
 
+	// Stdout и Stderr содержат весь вывод джоба, обрезанный до лимита воркера.
+	//
+	// Пока джоб выполняется, тот же вывод приходит порциями в JobOutput.
 	Stdout, Stderr []byte
 
 	ExitCode int
@@ -18,6 +22,89 @@ type JobResult struct {
 	//
 	// Если Error == nil, значит джоб завершился успешно.
 	Error *string
+
+	// Reason задаёт машиночитаемую причину ошибки из Error.
+	//
+	// Пустой Reason означает, что джоб упал по любой другой причине.
+	Reason FailureReason
+
+	// Downloads перечисляет артефакты зависимостей, которые воркер скачал перед запуском джоба.
+	Downloads []ArtifactDownload
+
+	// StartedAt и FinishedAt задают время запуска первой команды джоба и время его завершения по часам воркера.
+	//
+	// Если джоб упал до запуска команд, StartedAt остаётся нулевым.
+	StartedAt, FinishedAt time.Time
+
+	// Cached выставлен, если команды джоба не запускались, потому что его артефакт уже лежал в кеше
+	// воркера. Stdout и Stderr при этом содержат вывод того запуска, который создал артефакт.
+	//
+	// Координатор присылает такой JobResult в StatusUpdate.JobFinished, если нашёл артефакт через
+	// scheduler.Scheduler.LocateArtifact.
+	//
+	// StartedAt, FinishedAt и Downloads относятся к попаданию в кеш, а не к оригинальному запуску,
+	// см. MarkCached.
+	Cached bool
+}
+
+// MarkCached превращает сохранённый результат оригинального запуска в результат попадания в кеш в момент now.
+//
+// Вывод и код выхода остаются от оригинального запуска, а время запуска и завершения становятся равны now.
+// Иначе журнал событий показал бы давно завершившийся запуск и скачивания артефактов, которых в этой
+// сборке не было.
+func (r *JobResult) MarkCached(now time.Time) {
+	r.Cached = true
+	r.StartedAt = now
+	r.FinishedAt = now
+	r.Downloads = nil
+}
+
+// ArtifactDownload описывает скачивание одного артефакта зависимости.
+type ArtifactDownload struct {
+	ID build.ID
+
+	// From задаёт воркера или удалённый кеш, с которого скачан артефакт.
+	From WorkerID
+
+	// Size задаёт размер скачанных данных в байтах.
+	Size int64
+
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// FailureReason описывает причину, по которой джоб не удалось выполнить.
+type FailureReason string
+
+const (
+	// FailureCanceled означает, что воркер остановил джоб по запросу координатора.
+	FailureCanceled FailureReason = "canceled"
+
+	// FailureTimeout означает, что джоб не уложился в build.Limits.Timeout.
+	FailureTimeout FailureReason = "timeout"
+
+	// FailureOOM означает, что джоб был убит за превышение build.Limits.Memory.
+	FailureOOM FailureReason = "oom"
+
+	// FailureCPULimit означает, что джоб был убит за превышение build.Limits.CPUTime.
+	FailureCPULimit FailureReason = "cpu_limit"
+)
+
+// JobOutput содержит очередную порцию вывода джоба, который ещё выполняется.
+//
+// Склеенные по порядку порции JobOutput одного джоба совпадают с началом JobResult.Stdout и JobResult.Stderr.
+// Если джоб выполняется на нескольких воркерах, координатор пересылает сборкам вывод только одной копии.
+type JobOutput struct {
+	ID build.ID
+
+	Stdout, Stderr []byte
+
+	// Attempt задаёт номер запуска джоба, к которому относится порция, начиная с нуля.
+	//
+	// Воркер оставляет Attempt нулевым, а координатор увеличивает его, когда вывод джоба начинает приходить
+	// с другого воркера, например, после OnWorkerLost. Вывод нового запуска идёт с самого начала, поэтому
+	// правило о начале JobResult.Stdout и JobResult.Stderr выполняется для порций с последним Attempt.
+	Attempt int
 }
 
 type WorkerID string
@@ -41,12 +128,23 @@ type HeartbeatRequest struct {
 	// FreeSlots сообщает, сколько еще процессов можно запустить на этом воркере.
 	FreeSlots int
 
+	// Labels описывает свойства воркера: build.LabelOS, build.LabelArch, версии инструментов и произвольные теги.
+	//
+	// Координатор отдаёт воркеру только те джобы, чьи build.Job.Requires удовлетворяются этими метками.
+	Labels build.Labels
+
 	// JobResult сообщает координатору, какие джобы завершили исполнение на этом воркере
 	// на этой итерации цикла.
 	FinishedJob []JobResult
 
+	// JobOutput содержит вывод бегущих джобов, накопленный на этой итерации цикла.
+	JobOutput []JobOutput
+
 	// AddedArtifacts говорит, какие артефакты появились в кеше на этой итерации цикла.
 	AddedArtifacts []build.ID
+
+	// RemovedArtifacts говорит, какие артефакты были удалены из кеша на этой итерации цикла.
+	RemovedArtifacts []build.ID
 }
 
 // JobSpec описывает джоб, который нужно запустить.
@@ -54,6 +152,10 @@ type JobSpec struct {
 	// SourceFiles задаёт список файлов, который должны присутствовать в директории с исходным кодом при запуске этого джоба.
 	SourceFiles map[build.ID]string
 
+	// SourceCopies задаёт дополнительные пути, содержимое которых совпадает с одним из файлов SourceFiles.
+	// Воркер копирует эти файлы из кеша так же, как файлы из SourceFiles.
+	SourceCopies map[string]build.ID
+
 	// Artifacts задаёт воркеров, с которых можно скачать артефакты необходимые этому джобу.
 	Artifacts map[build.ID]WorkerID
 
@@ -62,6 +164,17 @@ type JobSpec struct {
 
 type HeartbeatResponse struct {
 	JobsToRun map[build.ID]JobSpec
+
+	// JobsToCancel перечисляет джобы, которые воркер должен остановить.
+	//
+	// Воркер убивает процессы этих джобов и присылает для каждого из них JobResult с Reason == FailureCanceled.
+	JobsToCancel []build.ID
+
+	// ReportAllArtifacts просит воркера прислать в следующем heartbeat полный список артефактов из своего кеша
+	// в AddedArtifacts.
+	//
+	// Координатор выставляет этот флаг, когда воркер, объявленный мёртвым, снова присылает heartbeat.
+	ReportAllArtifacts bool
 }
 
 type HeartbeatService interface {
@@ -4,6 +4,7 @@ package api
 
 import (
 	"context"
+	"net/http"
 
 	"go.uber.org/zap"
 )
@@ -15,6 +16,11 @@ func NewHeartbeatClient(l *zap.Logger, endpoint string) *HeartbeatClient {
 	panic("implement me")
 }
 
+// NewHeartbeatClientWithHTTP работает так же, как NewHeartbeatClient, но посылает запросы через httpClient.
+func NewHeartbeatClientWithHTTP(l *zap.Logger, endpoint string, httpClient *http.Client) *HeartbeatClient {
+	panic("implement me")
+}
+
 func (c *HeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
 	panic("implement me")
 }
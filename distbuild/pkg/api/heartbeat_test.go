@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -31,13 +32,21 @@ func TestHeartbeat(t *testing.T) {
 
 	client := api.NewHeartbeatClient(l, server.URL)
 
+	canceled := "job canceled"
 	req := &api.HeartbeatRequest{
 		WorkerID: "worker0",
+		FinishedJob: []api.JobResult{
+			{ID: build.ID{0x02}, ExitCode: -1, Error: &canceled, Reason: api.FailureCanceled},
+		},
+		JobOutput: []api.JobOutput{
+			{ID: build.ID{0x03}, Stdout: []byte("compiling...\n")},
+		},
 	}
 	rsp := &api.HeartbeatResponse{
 		JobsToRun: map[build.ID]api.JobSpec{
 			{0x01}: {Job: build.Job{Name: "cc a.c"}},
 		},
+		JobsToCancel: []build.ID{{0x02}},
 	}
 
 	gomock.InOrder(
@@ -53,3 +62,23 @@ func TestHeartbeat(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "build error: foo bar")
 }
+
+func TestJobResultMarkCached(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	res := api.JobResult{
+		ID:         build.ID{'a'},
+		Stdout:     []byte("OK\n"),
+		Downloads:  []api.ArtifactDownload{{ID: build.ID{'b'}, From: "w1"}},
+		StartedAt:  start,
+		FinishedAt: start.Add(time.Second),
+	}
+
+	now := start.Add(time.Hour)
+	res.MarkCached(now)
+
+	require.True(t, res.Cached)
+	require.Equal(t, []byte("OK\n"), res.Stdout)
+	require.Equal(t, now, res.StartedAt)
+	require.Equal(t, now, res.FinishedAt)
+	require.Empty(t, res.Downloads)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// StatusLog хранит историю обновлений одной сборки.
+//
+// Координатор пишет в StatusLog все обновления сборки, а каждый подключившийся клиент
+// читает их через Follow. Так клиент, потерявший соединение, может получить все обновления заново.
+type StatusLog struct {
+	mu       sync.Mutex
+	started  *BuildStarted
+	updates  []*StatusUpdate
+	finished bool
+	changed  chan struct{}
+}
+
+func NewStatusLog(started *BuildStarted) *StatusLog {
+	return &StatusLog{
+		started: started,
+		changed: make(chan struct{}),
+	}
+}
+
+// Append добавляет обновление в историю.
+//
+// После BuildFinished или BuildFailed история считается завершённой и новые обновления игнорируются.
+func (l *StatusLog) Append(update *StatusUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.finished {
+		return
+	}
+
+	l.updates = append(l.updates, update)
+	if update.BuildFinished != nil || update.BuildFailed != nil {
+		l.finished = true
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Follow пишет в w всю историю сборки, начиная с BuildStarted, и ждёт новых обновлений.
+//
+// Follow возвращает nil после того, как записал в w последнее обновление сборки.
+func (l *StatusLog) Follow(ctx context.Context, w StatusWriter) error {
+	if err := w.Started(l.started); err != nil {
+		return err
+	}
+
+	for next := 0; ; {
+		l.mu.Lock()
+		updates, finished, changed := l.updates[next:], l.finished, l.changed
+		l.mu.Unlock()
+
+		for _, update := range updates {
+			if err := w.Updated(update); err != nil {
+				return err
+			}
+		}
+		next += len(updates)
+
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
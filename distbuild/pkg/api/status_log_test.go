@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+type statusRecorder struct {
+	started *api.BuildStarted
+	updates chan *api.StatusUpdate
+}
+
+func (r *statusRecorder) Started(rsp *api.BuildStarted) error {
+	r.started = rsp
+	return nil
+}
+
+func (r *statusRecorder) Updated(update *api.StatusUpdate) error {
+	r.updates <- update
+	return nil
+}
+
+func TestStatusLogReplay(t *testing.T) {
+	started := &api.BuildStarted{ID: build.ID{0x01}}
+	jobA := &api.StatusUpdate{JobFinished: &api.JobResult{ID: build.ID{'a'}}}
+	jobB := &api.StatusUpdate{JobFinished: &api.JobResult{ID: build.ID{'b'}}}
+	finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
+
+	log := api.NewStatusLog(started)
+	log.Append(jobA)
+
+	r := &statusRecorder{updates: make(chan *api.StatusUpdate, 10)}
+
+	done := make(chan error)
+	go func() {
+		done <- log.Follow(context.Background(), r)
+	}()
+
+	require.Equal(t, jobA, <-r.updates)
+
+	log.Append(jobB)
+	require.Equal(t, jobB, <-r.updates)
+
+	log.Append(finished)
+	require.Equal(t, finished, <-r.updates)
+	require.NoError(t, <-done)
+	require.Equal(t, started, r.started)
+
+	log.Append(jobA)
+
+	replay := &statusRecorder{updates: make(chan *api.StatusUpdate, 10)}
+	require.NoError(t, log.Follow(context.Background(), replay))
+	require.Len(t, replay.updates, 3)
+}
+
+func TestStatusLogCancel(t *testing.T) {
+	log := api.NewStatusLog(&api.BuildStarted{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	r := &statusRecorder{updates: make(chan *api.StatusUpdate, 10)}
+	require.ErrorIs(t, log.Follow(ctx, r), context.DeadlineExceeded)
+}
@@ -1,13 +1,19 @@
 package artifact
 
 import (
+	"container/list"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
@@ -18,16 +24,57 @@ var (
 	ErrReadLocked  = errors.New("artifact is locked for read")
 )
 
+// EvictionPolicy задаёт ограничения на размер кеша.
+//
+// Когда кеш выходит за ограничения, из него удаляются артефакты, которые дольше всего никто не читал.
+// Артефакты, на которые взят лок на чтение или на запись, никогда не удаляются. Кеш пробует удалить их
+// снова, когда последний лок будет отпущен.
+// Нулевое значение поля означает, что соответствующего ограничения нет.
+type EvictionPolicy struct {
+	// MaxBytes ограничивает суммарный размер файлов всех артефактов.
+	MaxBytes int64
+
+	// MaxEntries ограничивает количество артефактов.
+	MaxEntries int
+
+	// OnEvict вызывается после того, как артефакт удалён из кеша.
+	//
+	// Тем, кто получает кеш уже созданным, например, воркеру, следует подписаться через Cache.Subscribe.
+	OnEvict func(artifact build.ID)
+}
+
+func (p *EvictionPolicy) exceeded(size int64, entries int) bool {
+	return (p.MaxBytes > 0 && size > p.MaxBytes) || (p.MaxEntries > 0 && entries > p.MaxEntries)
+}
+
+type entry struct {
+	id   build.ID
+	size int64
+}
+
 type Cache struct {
-	tmpDir   string
-	cacheDir string
+	tmpDir    string
+	cacheDir  string
+	resultDir string
+	policy    EvictionPolicy
 
 	mu          sync.Mutex
 	writeLocked map[build.ID]struct{}
 	readLocked  map[build.ID]int
+	subscribers map[int]func(artifact build.ID)
+	nextSub     int
+
+	// lru хранит артефакты в порядке последнего чтения, самые свежие в начале списка.
+	lru     *list.List
+	entries map[build.ID]*list.Element
+	size    int64
 }
 
 func NewCache(root string) (*Cache, error) {
+	return NewCacheWithPolicy(root, EvictionPolicy{})
+}
+
+func NewCacheWithPolicy(root string, policy EvictionPolicy) (*Cache, error) {
 	tmpDir := filepath.Join(root, "tmp")
 
 	if err := os.RemoveAll(tmpDir); err != nil {
@@ -42,19 +89,203 @@ func NewCache(root string) (*Cache, error) {
 		return nil, err
 	}
 
+	resultDir := filepath.Join(root, "r")
+
 	for i := range 256 {
 		d := hex.EncodeToString([]byte{uint8(i)})
 		if err := os.MkdirAll(filepath.Join(cacheDir, d), 0777); err != nil {
 			return nil, err
 		}
+		if err := os.MkdirAll(filepath.Join(resultDir, d), 0777); err != nil {
+			return nil, err
+		}
 	}
 
-	return &Cache{
+	c := &Cache{
 		tmpDir:      tmpDir,
 		cacheDir:    cacheDir,
+		resultDir:   resultDir,
+		policy:      policy,
 		writeLocked: make(map[build.ID]struct{}),
 		readLocked:  make(map[build.ID]int),
-	}, nil
+		subscribers: make(map[int]func(artifact build.ID)),
+		lru:         list.New(),
+		entries:     make(map[build.ID]*list.Element),
+	}
+
+	if err := c.loadEntries(); err != nil {
+		return nil, err
+	}
+
+	c.evict(nil)
+	return c, nil
+}
+
+// loadEntries заполняет lru артефактами, которые остались на диске с прошлого запуска.
+//
+// Время последнего чтения не сохраняется между запусками, поэтому артефакты упорядочиваются по времени создания.
+func (c *Cache) loadEntries() error {
+	type loaded struct {
+		entry
+		modTime time.Time
+	}
+
+	var artifacts []loaded
+	err := c.Range(func(id build.ID) error {
+		path := filepath.Join(c.cacheDir, id.Path())
+
+		st, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		a := loaded{entry: entry{id: id}, modTime: st.ModTime()}
+		if c.policy.MaxBytes > 0 {
+			if a.size, err = dirSize(path); err != nil {
+				return err
+			}
+		}
+
+		artifacts = append(artifacts, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].modTime.Before(artifacts[j].modTime)
+	})
+
+	for _, a := range artifacts {
+		c.addEntry(a.entry)
+	}
+
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func (c *Cache) addEntry(e entry) {
+	c.entries[e.id] = c.lru.PushFront(&e)
+	c.size += e.size
+}
+
+func (c *Cache) removeEntry(id build.ID) {
+	if el, ok := c.entries[id]; ok {
+		c.size -= el.Value.(*entry).size
+		c.lru.Remove(el)
+		delete(c.entries, id)
+	}
+}
+
+// evict удаляет самые старые артефакты, пока кеш не уложится в EvictionPolicy.
+//
+// Артефакт keep не удаляется, даже если он один не помещается в кеш.
+func (c *Cache) evict(keep *build.ID) {
+	c.mu.Lock()
+
+	var victims []build.ID
+	size, entries := c.size, c.lru.Len()
+	for el := c.lru.Back(); el != nil && c.policy.exceeded(size, entries); el = el.Prev() {
+		e := el.Value.(*entry)
+
+		if keep != nil && e.id == *keep {
+			continue
+		}
+		if _, ok := c.writeLocked[e.id]; ok {
+			continue
+		}
+		if c.readLocked[e.id] > 0 {
+			continue
+		}
+
+		c.writeLocked[e.id] = struct{}{}
+		victims = append(victims, e.id)
+
+		size -= e.size
+		entries--
+	}
+
+	c.mu.Unlock()
+
+	for _, id := range victims {
+		err := c.removeFiles(id)
+
+		c.mu.Lock()
+		if err == nil {
+			c.removeEntry(id)
+		}
+		delete(c.writeLocked, id)
+
+		var subscribers []func(build.ID)
+		for _, fn := range c.subscribers {
+			subscribers = append(subscribers, fn)
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			continue
+		}
+
+		if c.policy.OnEvict != nil {
+			c.policy.OnEvict(id)
+		}
+		for _, fn := range subscribers {
+			fn(id)
+		}
+	}
+}
+
+// evictOnUnlock повторяет вытеснение после того, как с артефакта id сняли последний лок.
+//
+// evict пропускает залоченные артефакты, поэтому без повтора кеш мог бы остаться больше ограничений
+// до следующего Create.
+func (c *Cache) evictOnUnlock(id build.ID) {
+	c.mu.Lock()
+	_, writeLocked := c.writeLocked[id]
+	retry := !writeLocked && c.readLocked[id] == 0 && c.policy.exceeded(c.size, c.lru.Len())
+	c.mu.Unlock()
+
+	if retry {
+		c.evict(nil)
+	}
+}
+
+// Subscribe регистрирует fn, который вызывается после удаления каждого артефакта при вытеснении.
+//
+// Артефакты, удалённые через Remove, в fn не попадают. Вызов unsubscribe отменяет подписку.
+func (c *Cache) Subscribe(fn func(artifact build.ID)) (unsubscribe func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextSub
+	c.nextSub++
+	c.subscribers[id] = fn
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.subscribers, id)
+	}
 }
 
 func (c *Cache) readLock(id build.ID) error {
@@ -66,17 +297,21 @@ func (c *Cache) readLock(id build.ID) error {
 	}
 
 	c.readLocked[id]++
+	if el, ok := c.entries[id]; ok {
+		c.lru.MoveToFront(el)
+	}
 	return nil
 }
 
 func (c *Cache) readUnlock(id build.ID) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	c.readLocked[id]--
 	if c.readLocked[id] == 0 {
 		delete(c.readLocked, id)
 	}
+	c.mu.Unlock()
+
+	c.evictOnUnlock(id)
 }
 
 func (c *Cache) writeLock(id build.ID, remove bool) error {
@@ -103,9 +338,26 @@ func (c *Cache) writeLock(id build.ID, remove bool) error {
 
 func (c *Cache) writeUnlock(id build.ID) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	delete(c.writeLocked, id)
+	c.mu.Unlock()
+
+	c.evictOnUnlock(id)
+}
+
+// removeFiles удаляет с диска артефакт вместе с его результатом.
+func (c *Cache) removeFiles(id build.ID) error {
+	if err := os.RemoveAll(filepath.Join(c.cacheDir, id.Path())); err != nil {
+		return err
+	}
+
+	if err := os.Remove(c.resultPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) resultPath(id build.ID) string {
+	return filepath.Join(c.resultDir, id.Path()+".json")
 }
 
 func (c *Cache) Range(artifactFn func(artifact build.ID) error) error {
@@ -141,7 +393,14 @@ func (c *Cache) Remove(artifact build.ID) error {
 	}
 	defer c.writeUnlock(artifact)
 
-	return os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path()))
+	if err := c.removeFiles(artifact); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.removeEntry(artifact)
+	c.mu.Unlock()
+	return nil
 }
 
 func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() error, err error) {
@@ -161,8 +420,29 @@ func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() err
 	}
 
 	commit = func() error {
-		defer c.writeUnlock(artifact)
-		return os.Rename(path, filepath.Join(c.cacheDir, artifact.Path()))
+		e := entry{id: artifact}
+		if c.policy.MaxBytes > 0 {
+			size, err := dirSize(path)
+			if err != nil {
+				c.writeUnlock(artifact)
+				return err
+			}
+			e.size = size
+		}
+
+		if err := os.Rename(path, filepath.Join(c.cacheDir, artifact.Path())); err != nil {
+			c.writeUnlock(artifact)
+			return err
+		}
+
+		c.mu.Lock()
+		c.removeEntry(artifact)
+		c.addEntry(e)
+		delete(c.writeLocked, artifact)
+		c.mu.Unlock()
+
+		c.evict(&artifact)
+		return nil
 	}
 
 	return
@@ -188,3 +468,59 @@ func (c *Cache) Get(artifact build.ID) (path string, unlock func(), err error) {
 	}
 	return
 }
+
+// WriteResult сохраняет рядом с артефактом результат джоба, который его создал.
+//
+// Артефакт должен уже лежать в кеше. Результат удаляется из кеша вместе с артефактом.
+func (c *Cache) WriteResult(artifact build.ID, res *api.JobResult) error {
+	_, unlock, err := c.Get(artifact)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.tmpDir, artifact.String()+".*.json")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.resultPath(artifact))
+}
+
+// ReadResult возвращает результат джоба, сохранённый через WriteResult.
+//
+// Если артефакта или его результата нет в кеше, ReadResult возвращает ErrNotFound.
+func (c *Cache) ReadResult(artifact build.ID) (*api.JobResult, error) {
+	_, unlock, err := c.Get(artifact)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(c.resultPath(artifact))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res api.JobResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
@@ -96,3 +97,174 @@ func TestArtifactExists(t *testing.T) {
 	_, _, _, err = c.Create(idA)
 	require.Truef(t, errors.Is(err, artifact.ErrExists), "%v", err)
 }
+
+func newTestCacheWithPolicy(t *testing.T, policy artifact.EvictionPolicy) *testCache {
+	tmpDir := t.TempDir()
+
+	cache, err := artifact.NewCacheWithPolicy(tmpDir, policy)
+	require.NoError(t, err)
+
+	return &testCache{Cache: cache, tmpDir: tmpDir}
+}
+
+func (c *testCache) put(t *testing.T, id build.ID, content string) {
+	path, commit, _, err := c.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(path, "a.txt"), []byte(content), 0666))
+	require.NoError(t, commit())
+}
+
+func (c *testCache) has(id build.ID) bool {
+	_, unlock, err := c.Get(id)
+	if err != nil {
+		return false
+	}
+	unlock()
+	return true
+}
+
+func TestEvictionMaxEntries(t *testing.T) {
+	var evicted []build.ID
+	c := newTestCacheWithPolicy(t, artifact.EvictionPolicy{
+		MaxEntries: 2,
+		OnEvict:    func(id build.ID) { evicted = append(evicted, id) },
+	})
+
+	c.put(t, build.ID{'a'}, "a")
+	c.put(t, build.ID{'b'}, "b")
+
+	// Чтение делает артефакт a самым свежим.
+	require.True(t, c.has(build.ID{'a'}))
+
+	c.put(t, build.ID{'c'}, "c")
+
+	require.Equal(t, []build.ID{{'b'}}, evicted)
+	require.True(t, c.has(build.ID{'a'}))
+	require.False(t, c.has(build.ID{'b'}))
+	require.True(t, c.has(build.ID{'c'}))
+}
+
+func TestEvictionMaxBytes(t *testing.T) {
+	c := newTestCacheWithPolicy(t, artifact.EvictionPolicy{MaxBytes: 10})
+
+	c.put(t, build.ID{'a'}, "aaaa")
+	c.put(t, build.ID{'b'}, "bbbb")
+	c.put(t, build.ID{'c'}, "cccc")
+
+	require.False(t, c.has(build.ID{'a'}))
+	require.True(t, c.has(build.ID{'b'}))
+	require.True(t, c.has(build.ID{'c'}))
+
+	// Артефакт, который один не помещается в кеш, всё равно сохраняется.
+	c.put(t, build.ID{'d'}, "dddddddddddddddd")
+	require.True(t, c.has(build.ID{'d'}))
+	require.False(t, c.has(build.ID{'b'}))
+	require.False(t, c.has(build.ID{'c'}))
+}
+
+func TestEvictionSkipsLocked(t *testing.T) {
+	c := newTestCacheWithPolicy(t, artifact.EvictionPolicy{MaxEntries: 1})
+
+	c.put(t, build.ID{'a'}, "a")
+
+	_, unlock, err := c.Get(build.ID{'a'})
+	require.NoError(t, err)
+
+	c.put(t, build.ID{'b'}, "b")
+
+	var ids []build.ID
+	require.NoError(t, c.Range(func(id build.ID) error {
+		ids = append(ids, id)
+		return nil
+	}))
+	require.ElementsMatch(t, []build.ID{{'a'}, {'b'}}, ids)
+
+	// Снятие последнего лока повторяет вытеснение.
+	unlock()
+	require.False(t, c.has(build.ID{'a'}))
+	require.True(t, c.has(build.ID{'b'}))
+
+	c.put(t, build.ID{'c'}, "c")
+	require.False(t, c.has(build.ID{'b'}))
+	require.True(t, c.has(build.ID{'c'}))
+}
+
+func TestEvictionSubscribe(t *testing.T) {
+	c := newTestCacheWithPolicy(t, artifact.EvictionPolicy{MaxEntries: 1})
+
+	var evicted []build.ID
+	unsubscribe := c.Subscribe(func(id build.ID) { evicted = append(evicted, id) })
+
+	c.put(t, build.ID{'a'}, "a")
+	c.put(t, build.ID{'b'}, "b")
+	require.NoError(t, c.Remove(build.ID{'b'}))
+	require.Equal(t, []build.ID{{'a'}}, evicted)
+
+	unsubscribe()
+	c.put(t, build.ID{'c'}, "c")
+	c.put(t, build.ID{'d'}, "d")
+	require.Equal(t, []build.ID{{'a'}}, evicted)
+}
+
+func TestEvictionOnRestart(t *testing.T) {
+	c := newTestCache(t)
+
+	c.put(t, build.ID{'a'}, "a")
+	c.put(t, build.ID{'b'}, "b")
+
+	restarted, err := artifact.NewCacheWithPolicy(c.tmpDir, artifact.EvictionPolicy{MaxEntries: 1})
+	require.NoError(t, err)
+
+	var ids []build.ID
+	require.NoError(t, restarted.Range(func(id build.ID) error {
+		ids = append(ids, id)
+		return nil
+	}))
+	require.Len(t, ids, 1)
+}
+
+func TestJobResult(t *testing.T) {
+	c := newTestCache(t)
+
+	idA := build.ID{'a'}
+	res := &api.JobResult{ID: idA, Stdout: []byte("OK\n"), Stderr: []byte("warning\n")}
+
+	require.ErrorIs(t, c.WriteResult(idA, res), artifact.ErrNotFound)
+
+	c.put(t, idA, "a")
+
+	_, err := c.ReadResult(idA)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+
+	require.NoError(t, c.WriteResult(idA, res))
+
+	stored, err := c.ReadResult(idA)
+	require.NoError(t, err)
+	require.Equal(t, res, stored)
+
+	restarted, err := artifact.NewCache(c.tmpDir)
+	require.NoError(t, err)
+
+	stored, err = restarted.ReadResult(idA)
+	require.NoError(t, err)
+	require.Equal(t, res, stored)
+
+	require.NoError(t, restarted.Remove(idA))
+	_, err = restarted.ReadResult(idA)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+func TestEvictionRemovesJobResult(t *testing.T) {
+	c := newTestCacheWithPolicy(t, artifact.EvictionPolicy{MaxEntries: 1})
+
+	c.put(t, build.ID{'a'}, "a")
+	require.NoError(t, c.WriteResult(build.ID{'a'}, &api.JobResult{ID: build.ID{'a'}}))
+
+	c.put(t, build.ID{'b'}, "b")
+
+	_, err := c.ReadResult(build.ID{'a'})
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+
+	_, err = os.Stat(filepath.Join(c.tmpDir, "r", build.ID{'a'}.Path()+".json"))
+	require.True(t, os.IsNotExist(err), "%v", err)
+}
@@ -4,11 +4,38 @@ package artifact
 
 import (
 	"context"
+	"net/http"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
 // Download artifact from remote cache into local cache.
+//
+// Вместе с артефактом Download переносит его результат, если он есть: см. DownloadResult и Cache.WriteResult.
 func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
 	panic("implement me")
 }
+
+// Upload artifact from local cache into remote cache.
+func Upload(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
+	panic("implement me")
+}
+
+// DownloadWithHTTP работает так же, как Download, но посылает запрос через httpClient.
+func DownloadWithHTTP(ctx context.Context, httpClient *http.Client, endpoint string, c *Cache, artifactID build.ID) error {
+	panic("implement me")
+}
+
+// UploadWithHTTP работает так же, как Upload, но посылает запрос через httpClient.
+func UploadWithHTTP(ctx context.Context, httpClient *http.Client, endpoint string, c *Cache, artifactID build.ID) error {
+	panic("implement me")
+}
+
+// DownloadResult скачивает результат джоба, сохранённый рядом с артефактом через Cache.WriteResult.
+//
+// Если на endpoint нет артефакта или его результата, DownloadResult возвращает ошибку, для которой
+// errors.Is(err, ErrNotFound). Нулевой httpClient означает http.DefaultClient.
+func DownloadResult(ctx context.Context, httpClient *http.Client, endpoint string, artifactID build.ID) (*api.JobResult, error) {
+	panic("implement me")
+}
@@ -1,6 +1,7 @@
 package artifact_test
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +14,8 @@ import (
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/compression"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 func TestArtifactTransfer(t *testing.T) {
@@ -49,3 +52,41 @@ func TestArtifactTransfer(t *testing.T) {
 	err = artifact.Download(ctx, server.URL, localCache.Cache, build.ID{0x02})
 	require.Error(t, err)
 }
+
+func TestArtifactCompression(t *testing.T) {
+	remoteCache := newTestCache(t)
+
+	id := build.ID{0x01}
+
+	dir, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), bytes.Repeat([]byte("foobar"), 1024), 0777))
+	require.NoError(t, commit())
+
+	mux := http.NewServeMux()
+	artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/artifact?id="+id.String(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", compression.AcceptEncoding)
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, compression.Gzip, rsp.Header.Get("Content-Encoding"))
+
+	r, err := compression.NewReader(rsp.Body, rsp.Header.Get("Content-Encoding"))
+	require.NoError(t, err)
+
+	to := t.TempDir()
+	require.NoError(t, tarstream.Receive(to, r))
+
+	content, err := os.ReadFile(filepath.Join(to, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte("foobar"), 1024), content)
+}
@@ -0,0 +1,72 @@
+package artifact
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// LocateFunc возвращает endpoint, с которого можно скачать артефакт.
+type LocateFunc func(id build.ID) (endpoint string, ok bool)
+
+// Proxy отвечает на GET /artifact?id=1234, пересылая запрос тому, кто хранит артефакт.
+//
+// Координатор использует Proxy, чтобы клиент мог скачать результаты сборки, не подключаясь к воркерам напрямую.
+// Тело ответа и заголовки, в том числе Content-Encoding, передаются без изменений. Заголовок Authorization
+// с токеном клиента воркерам не пересылается.
+type Proxy struct {
+	l      *zap.Logger
+	locate LocateFunc
+
+	// Transport задаёт, через что Proxy ходит к воркерам. Нулевое значение означает http.DefaultTransport.
+	//
+	// Если воркеры требуют клиентский сертификат, передайте сюда Transport из auth.Authenticator.HTTPClient.
+	Transport http.RoundTripper
+}
+
+func NewProxy(l *zap.Logger, locate LocateFunc) *Proxy {
+	return &Proxy{l: l, locate: locate}
+}
+
+func (p *Proxy) Register(mux *http.ServeMux) {
+	mux.Handle("GET /artifact", p)
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endpoint, ok := p.locate(id)
+	if !ok {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.l.Debug("proxying artifact", zap.Stringer("artifact_id", id), zap.String("endpoint", endpoint))
+
+	proxy := &httputil.ReverseProxy{
+		Transport: p.Transport,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Header.Del("Authorization")
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			p.l.Warn("artifact proxy failed", zap.Stringer("artifact_id", id), zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
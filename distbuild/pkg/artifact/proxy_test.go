@@ -0,0 +1,117 @@
+package artifact_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/compression"
+)
+
+func TestArtifactProxy(t *testing.T) {
+	id := build.ID{0x01}
+
+	// Хендлер работает в горутине сервера, поэтому проверки делаются после того, как запрос вернулся.
+	var gotID, gotAcceptEncoding string
+
+	worker := http.NewServeMux()
+	worker.HandleFunc("/worker/0/artifact", func(w http.ResponseWriter, r *http.Request) {
+		gotID = r.URL.Query().Get("id")
+		gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+
+		w.Header().Set("Content-Encoding", compression.Gzip)
+		_, _ = w.Write([]byte("compressed tarstream"))
+	})
+
+	workerServer := httptest.NewServer(worker)
+	defer workerServer.Close()
+
+	proxy := artifact.NewProxy(zaptest.NewLogger(t), func(artifactID build.ID) (string, bool) {
+		return workerServer.URL + "/worker/0", artifactID == id
+	})
+
+	mux := http.NewServeMux()
+	proxy.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(id build.ID) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/artifact?id="+id.String(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", compression.AcceptEncoding)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = rsp.Body.Close() })
+		return rsp
+	}
+
+	rsp := get(id)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, compression.Gzip, rsp.Header.Get("Content-Encoding"))
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "compressed tarstream", string(body))
+	require.Equal(t, id.String(), gotID)
+	require.Equal(t, compression.AcceptEncoding, gotAcceptEncoding)
+
+	require.Equal(t, http.StatusNotFound, get(build.ID{0x02}).StatusCode)
+}
+
+func TestArtifactProxyTransport(t *testing.T) {
+	workerServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tarstream"))
+	}))
+	defer workerServer.Close()
+
+	proxy := artifact.NewProxy(zaptest.NewLogger(t), func(build.ID) (string, bool) {
+		return workerServer.URL, true
+	})
+
+	mux := http.NewServeMux()
+	proxy.Register(mux)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/artifact?id="+build.ID{0x01}.String(), nil))
+		return w
+	}
+
+	require.Equal(t, http.StatusBadGateway, get().Code)
+
+	proxy.Transport = workerServer.Client().Transport
+
+	w := get()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "tarstream", w.Body.String())
+}
+
+func TestArtifactProxyStripsAuthorization(t *testing.T) {
+	var authorization []string
+	workerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Values("Authorization")
+		_, _ = w.Write([]byte("tarstream"))
+	}))
+	defer workerServer.Close()
+
+	proxy := artifact.NewProxy(zaptest.NewLogger(t), func(build.ID) (string, bool) {
+		return workerServer.URL, true
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/artifact?id="+build.ID{0x01}.String(), nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, authorization)
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrNotWorker       = errors.New("peer is not authenticated as a worker")
+	ErrTokensWithoutCA = errors.New("tokens require a CA to authenticate workers")
+	ErrNoServerCert    = errors.New("server authentication requires a certificate")
+)
+
+// Config задаёт сертификаты и токены одной компоненты.
+//
+// Нулевое значение Config выключает аутентификацию.
+type Config struct {
+	// CAFile задаёт PEM файл с сертификатом CA, которым подписаны сертификаты координатора и воркеров.
+	CAFile string
+
+	// CertFile и KeyFile задают сертификат и ключ этой компоненты в формате PEM.
+	//
+	// Один и тот же сертификат используется и как серверный, и как клиентский.
+	CertFile, KeyFile string
+
+	// Tokens перечисляет bearer-токены, с которыми сервер принимает запросы клиентов.
+	//
+	// Tokens требует CAFile: иначе сервер не сможет проверить сертификаты воркеров и будет отвергать
+	// их запросы.
+	Tokens []string
+
+	// Token задаёт bearer-токен, который клиент посылает в заголовке Authorization.
+	Token string
+}
+
+// ReadTokens читает токены из файла path, по одному на строку. Пустые строки пропускаются.
+func ReadTokens(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if token := strings.TrimSpace(line); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// Identity описывает, как аутентифицирован собеседник.
+type Identity struct {
+	// Certificate содержит клиентский сертификат, подписанный CA. Так аутентифицируются воркеры и координатор.
+	Certificate *x509.Certificate
+
+	// Token выставлен, если собеседник предъявил один из Config.Tokens. Так аутентифицируются клиенты.
+	Token bool
+}
+
+// VerifyWorker проверяет, что собеседник может выступать от имени воркера с адресом endpoint.
+//
+// Сертификат собеседника должен быть выписан на хост из endpoint. Воркеры, запущенные на одном хосте,
+// друг от друга не отличаются.
+func (id *Identity) VerifyWorker(endpoint string) error {
+	if id == nil || id.Certificate == nil {
+		return ErrNotWorker
+	}
+
+	host, err := endpointHost(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := id.Certificate.VerifyHostname(host); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotWorker, err)
+	}
+	return nil
+}
+
+func endpointHost(endpoint string) (string, error) {
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", err
+		}
+		return u.Hostname(), nil
+	}
+
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint, nil
+	}
+	return host, nil
+}
+
+type identityKey struct{}
+
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext возвращает Identity, которую положили в контекст Middleware или gRPC интерсепторы.
+//
+// Если аутентификация выключена, FromContext возвращает nil.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Authenticator проверяет сертификаты и токены собеседников и настраивает TLS.
+type Authenticator struct {
+	config Config
+
+	roots  *x509.CertPool
+	cert   *tls.Certificate
+	tokens [][]byte
+}
+
+// New загружает сертификаты из config.
+//
+// New возвращает ErrTokensWithoutCA, если Config.Tokens задан без Config.CAFile, и ErrNoServerCert,
+// если Config.Tokens задан без Config.CertFile.
+func New(config Config) (*Authenticator, error) {
+	if len(config.Tokens) != 0 && config.CAFile == "" {
+		return nil, ErrTokensWithoutCA
+	}
+
+	if len(config.Tokens) != 0 && config.CertFile == "" {
+		return nil, ErrNoServerCert
+	}
+
+	a := &Authenticator{config: config}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		a.roots = x509.NewCertPool()
+		if !a.roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+		}
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.cert = &cert
+	}
+
+	for _, token := range config.Tokens {
+		a.tokens = append(a.tokens, []byte(token))
+	}
+
+	return a, nil
+}
+
+// NewServer работает так же, как New, но загружает настройки сервера: координатора, воркера или удалённого кеша.
+//
+// Если сервер проверяет запросы, NewServer требует Config.CertFile и иначе возвращает ErrNoServerCert.
+// Без сертификата сервер работал бы без TLS: воркеры не смогли бы предъявить свои сертификаты, а токены
+// клиентов передавались бы открытым текстом.
+func NewServer(config Config) (*Authenticator, error) {
+	a, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Enabled() && a.cert == nil {
+		return nil, ErrNoServerCert
+	}
+
+	return a, nil
+}
+
+// Enabled сообщает, должен ли сервер проверять входящие запросы.
+func (a *Authenticator) Enabled() bool {
+	return a.roots != nil || len(a.tokens) != 0
+}
+
+// ServerTLS возвращает настройки TLS для сервера или nil, если сертификат не задан.
+//
+// Сервер запрашивает у собеседника клиентский сертификат, но не требует его: клиенты вместо
+// сертификата предъявляют токен.
+func (a *Authenticator) ServerTLS() *tls.Config {
+	if a.cert == nil {
+		return nil
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{*a.cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if a.roots != nil {
+		config.ClientCAs = a.roots
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config
+}
+
+// ClientTLS возвращает настройки TLS для исходящих соединений.
+func (a *Authenticator) ClientTLS() *tls.Config {
+	config := &tls.Config{
+		RootCAs:    a.roots,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if a.cert != nil {
+		config.Certificates = []tls.Certificate{*a.cert}
+	}
+
+	return config
+}
+
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(r)
+}
+
+// HTTPClient возвращает http.Client, который предъявляет сертификат и токен из Config.
+func (a *Authenticator) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = a.ClientTLS()
+
+	var rt http.RoundTripper = transport
+	if a.config.Token != "" {
+		rt = &tokenTransport{token: a.config.Token, next: transport}
+	}
+
+	return &http.Client{Transport: rt}
+}
+
+func (a *Authenticator) checkToken(header string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+
+	valid := false
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (a *Authenticator) authenticate(state *tls.ConnectionState, authorization string) (*Identity, error) {
+	id := &Identity{}
+
+	if state != nil && len(state.VerifiedChains) != 0 {
+		id.Certificate = state.VerifiedChains[0][0]
+	}
+
+	id.Token = a.checkToken(authorization)
+
+	if id.Certificate == nil && !id.Token {
+		return nil, ErrUnauthenticated
+	}
+	return id, nil
+}
+
+// Authenticate проверяет клиентский сертификат и заголовок Authorization запроса r.
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	return a.authenticate(r.TLS, r.Header.Get("Authorization"))
+}
+
+// Middleware отвечает 401 на запросы, которые не прошли Authenticate, а остальным запросам
+// кладёт Identity в контекст.
+//
+// Если аутентификация выключена, Middleware возвращает next без изменений.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
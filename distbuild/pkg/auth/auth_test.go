@@ -0,0 +1,203 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth/authtest"
+)
+
+func newAuthenticator(t *testing.T, config auth.Config) *auth.Authenticator {
+	a, err := auth.New(config)
+	require.NoError(t, err)
+	return a
+}
+
+func newServer(t *testing.T, config auth.Config) (*httptest.Server, chan *auth.Identity) {
+	a := newAuthenticator(t, config)
+	identities := make(chan *auth.Identity, 1)
+
+	server := httptest.NewUnstartedServer(a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identities <- auth.FromContext(r.Context())
+		_, _ = io.WriteString(w, "OK")
+	})))
+	server.TLS = a.ServerTLS()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, identities
+}
+
+func get(t *testing.T, client *http.Client, url string) int {
+	rsp, err := client.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+	return rsp.StatusCode
+}
+
+func TestHTTP(t *testing.T) {
+	ca := authtest.NewCA(t)
+
+	serverConfig := ca.Issue(t, "127.0.0.1")
+	serverConfig.Tokens = []string{"secret"}
+	server, identities := newServer(t, serverConfig)
+
+	worker := newAuthenticator(t, ca.Issue(t, "worker.example.com"))
+	require.Equal(t, http.StatusOK, get(t, worker.HTTPClient(), server.URL))
+
+	id := <-identities
+	require.NotNil(t, id.Certificate)
+	require.False(t, id.Token)
+	require.NoError(t, id.VerifyWorker("http://worker.example.com:8081/worker/0"))
+	require.NoError(t, id.VerifyWorker("worker.example.com:8081"))
+	require.ErrorIs(t, id.VerifyWorker("http://other.example.com:8081"), auth.ErrNotWorker)
+
+	client := newAuthenticator(t, auth.Config{CAFile: ca.CAFile, Token: "secret"})
+	require.Equal(t, http.StatusOK, get(t, client.HTTPClient(), server.URL))
+
+	id = <-identities
+	require.Nil(t, id.Certificate)
+	require.True(t, id.Token)
+	require.ErrorIs(t, id.VerifyWorker("http://127.0.0.1:8081"), auth.ErrNotWorker)
+
+	wrongToken := newAuthenticator(t, auth.Config{CAFile: ca.CAFile, Token: "guess"})
+	require.Equal(t, http.StatusUnauthorized, get(t, wrongToken.HTTPClient(), server.URL))
+
+	anonymous := newAuthenticator(t, auth.Config{CAFile: ca.CAFile})
+	require.Equal(t, http.StatusUnauthorized, get(t, anonymous.HTTPClient(), server.URL))
+}
+
+func TestForeignCA(t *testing.T) {
+	ca := authtest.NewCA(t)
+	server, _ := newServer(t, ca.Issue(t, "127.0.0.1"))
+
+	other := authtest.NewCA(t)
+	config := other.Issue(t, "worker.example.com")
+	config.CAFile = ca.CAFile
+
+	_, err := newAuthenticator(t, config).HTTPClient().Get(server.URL)
+	require.Error(t, err)
+}
+
+func TestReadTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("alice\n\n  bob \n"), 0600))
+
+	tokens, err := auth.ReadTokens(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob"}, tokens)
+}
+
+func TestTokensWithoutCA(t *testing.T) {
+	_, err := auth.New(auth.Config{Tokens: []string{"secret"}})
+	require.ErrorIs(t, err, auth.ErrTokensWithoutCA)
+
+	// Клиенту CA не нужен, если координатор доступен без TLS.
+	_, err = auth.New(auth.Config{Token: "secret"})
+	require.NoError(t, err)
+}
+
+func TestServerWithoutCert(t *testing.T) {
+	ca := authtest.NewCA(t)
+
+	_, err := auth.New(auth.Config{CAFile: ca.CAFile, Tokens: []string{"secret"}})
+	require.ErrorIs(t, err, auth.ErrNoServerCert)
+
+	_, err = auth.NewServer(auth.Config{CAFile: ca.CAFile})
+	require.ErrorIs(t, err, auth.ErrNoServerCert)
+
+	// Клиент проверяет сертификат сервера, но своего не предъявляет.
+	_, err = auth.New(auth.Config{CAFile: ca.CAFile})
+	require.NoError(t, err)
+
+	_, err = auth.NewServer(ca.Issue(t, "127.0.0.1"))
+	require.NoError(t, err)
+
+	_, err = auth.NewServer(auth.Config{})
+	require.NoError(t, err)
+}
+
+func TestDisabled(t *testing.T) {
+	a := newAuthenticator(t, auth.Config{})
+	require.False(t, a.Enabled())
+	require.Nil(t, a.ServerTLS())
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, auth.FromContext(r.Context()))
+	})
+
+	w := httptest.NewRecorder()
+	a.Middleware(handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+type heartbeatService struct {
+	identities chan *auth.Identity
+}
+
+func (s *heartbeatService) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+	id := auth.FromContext(ctx)
+	s.identities <- id
+
+	if err := id.VerifyWorker(string(req.WorkerID)); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return &api.HeartbeatResponse{}, nil
+}
+
+func TestGRPC(t *testing.T) {
+	ca := authtest.NewCA(t)
+	log := zaptest.NewLogger(t)
+
+	serverConfig := ca.Issue(t, "127.0.0.1")
+	serverConfig.Tokens = []string{"secret"}
+	a := newAuthenticator(t, serverConfig)
+
+	service := &heartbeatService{identities: make(chan *auth.Identity, 1)}
+	server := grpc.NewServer(a.GRPCServerOptions()...)
+	api.NewGRPCHeartbeatHandler(log, service).Register(server)
+
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = server.Serve(lsn) }()
+	t.Cleanup(server.Stop)
+
+	heartbeat := func(config auth.Config, workerID api.WorkerID) error {
+		conn, err := api.DialGRPC(lsn.Addr().String(), newAuthenticator(t, config).GRPCDialOptions()...)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = api.NewGRPCHeartbeatClient(log, conn).Heartbeat(context.Background(), &api.HeartbeatRequest{WorkerID: workerID})
+		return err
+	}
+
+	workerConfig := ca.Issue(t, "worker.example.com")
+	require.NoError(t, heartbeat(workerConfig, "http://worker.example.com:8081"))
+	require.NotNil(t, (<-service.identities).Certificate)
+
+	err = heartbeat(workerConfig, "http://other.example.com:8081")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	<-service.identities
+
+	err = heartbeat(auth.Config{CAFile: ca.CAFile, Token: "secret"}, "http://worker.example.com:8081")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.True(t, (<-service.identities).Token)
+
+	err = heartbeat(auth.Config{CAFile: ca.CAFile}, "http://worker.example.com:8081")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
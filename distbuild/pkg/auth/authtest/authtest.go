@@ -0,0 +1,105 @@
+package authtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// CA - одноразовый центр сертификации, файлы которого лежат во временной директории теста.
+type CA struct {
+	dir    string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+
+	// CAFile содержит путь до сертификата CA в формате PEM.
+	CAFile string
+}
+
+func NewCA(t testing.TB) *CA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "distbuild test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &CA{dir: t.TempDir(), cert: cert, key: key, serial: 1}
+	ca.CAFile = filepath.Join(ca.dir, "ca.pem")
+	writePEM(t, ca.CAFile, "CERTIFICATE", der)
+	return ca
+}
+
+// Issue выписывает сертификат на хосты hosts и возвращает auth.Config, который его использует.
+//
+// Элементы hosts, похожие на IP адрес, попадают в IPAddresses, остальные в DNSNames.
+func (ca *CA) Issue(t testing.TB, hosts ...string) auth.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	name := filepath.Join(ca.dir, big.NewInt(ca.serial).String())
+	config := auth.Config{
+		CAFile:   ca.CAFile,
+		CertFile: name + ".pem",
+		KeyFile:  name + ".key",
+	}
+
+	writePEM(t, config.CertFile, "CERTIFICATE", der)
+	writePEM(t, config.KeyFile, "PRIVATE KEY", keyDER)
+	return config
+}
+
+func writePEM(t testing.TB, path, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (a *Authenticator) authenticateGRPC(ctx context.Context) (context.Context, error) {
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) != 0 {
+			authorization = values[0]
+		}
+	}
+
+	id, err := a.authenticate(state, authorization)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return NewContext(ctx, id), nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// GRPCServerOptions возвращает опции grpc.Server, включающие TLS из ServerTLS и проверку
+// собеседника так же, как в Middleware.
+func (a *Authenticator) GRPCServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if config := a.ServerTLS(); config != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+
+	if !a.Enabled() {
+		return opts
+	}
+
+	return append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := a.authenticateGRPC(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := a.authenticateGRPC(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+		}),
+	)
+}
+
+type tokenCredentials struct {
+	token   string
+	withTLS bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.withTLS
+}
+
+// GRPCDialOptions возвращает опции соединения, которые предъявляют сертификат и токен из Config.
+//
+// Если ни CAFile, ни CertFile не заданы, соединение устанавливается без TLS.
+func (a *Authenticator) GRPCDialOptions() []grpc.DialOption {
+	withTLS := a.roots != nil || a.cert != nil
+
+	var opts []grpc.DialOption
+	if withTLS {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(a.ClientTLS())))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if a.config.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: a.config.Token, withTLS: withTLS}))
+	}
+
+	return opts
+}
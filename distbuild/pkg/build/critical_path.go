@@ -0,0 +1,33 @@
+package build
+
+import "time"
+
+// CriticalPath computes remaining critical path length for every job.
+//
+// Remaining critical path of a job is its own estimated duration plus the longest remaining
+// critical path among the jobs that depend on it. Jobs with longer critical path should be started first.
+// Graph must be valid, see Validate.
+func CriticalPath(jobs []Job, estimate func(job *Job) time.Duration) map[ID]time.Duration {
+	dependents := map[ID][]ID{}
+	for _, job := range jobs {
+		for _, dep := range job.Deps {
+			dependents[dep] = append(dependents[dep], job.ID)
+		}
+	}
+
+	sorted := TopSort(jobs)
+	path := make(map[ID]time.Duration, len(sorted))
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		job := &sorted[i]
+
+		var longest time.Duration
+		for _, next := range dependents[job.ID] {
+			longest = max(longest, path[next])
+		}
+
+		path[job.ID] = estimate(job) + longest
+	}
+
+	return path
+}
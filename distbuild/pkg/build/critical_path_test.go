@@ -0,0 +1,32 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCriticalPath(t *testing.T) {
+	jobs := []Job{
+		{ID: ID{'a'}, Name: "a"},
+		{ID: ID{'b'}, Name: "b", Deps: []ID{{'a'}}},
+		{ID: ID{'c'}, Name: "c", Deps: []ID{{'b'}}},
+		{ID: ID{'d'}, Name: "d", Deps: []ID{{'a'}}},
+		{ID: ID{'e'}, Name: "e"},
+	}
+
+	durations := map[string]time.Duration{"a": 1, "b": 2, "c": 3, "d": 10, "e": 1}
+
+	path := CriticalPath(jobs, func(job *Job) time.Duration {
+		return durations[job.Name]
+	})
+
+	require.Equal(t, map[ID]time.Duration{
+		{'a'}: 11,
+		{'b'}: 5,
+		{'c'}: 3,
+		{'d'}: 10,
+		{'e'}: 1,
+	}, path)
+}
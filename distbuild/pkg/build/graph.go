@@ -1,10 +1,13 @@
 package build
 
+import "time"
+
 // Job описывает одну вершину графа сборки.
 type Job struct {
 	// ID задаёт уникальный идентификатор джоба.
 	//
-	// ID вычисляется как хеш от всех входных файлов, команд запуска и хешей зависимых джобов.
+	// ID вычисляется как хеш от всех входных файлов, команд запуска, ограничений Limits,
+	// требований Requires и хешей зависимых джобов.
 	//
 	// Выход джоба целиком определяется его ID. Это важное свойство позволяет кешировать
 	// результаты сборки.
@@ -29,6 +32,29 @@ type Job struct {
 
 	// Cmds описывает список команд, которые нужно выполнить в рамках этого джоба.
 	Cmds []Cmd
+
+	// Limits задаёт ограничения на ресурсы, которые может потратить джоб.
+	Limits Limits
+
+	// Requires перечисляет метки, которые должны быть у воркера, чтобы он мог выполнить этот джоб.
+	//
+	// Например, {"arch": "arm64", "go": "go1.22.1"}. Пустой Requires означает, что джоб можно
+	// выполнить на любом воркере.
+	Requires Labels
+}
+
+// Limits описывает ограничения на ресурсы джоба.
+//
+// Нулевое значение любого поля означает, что соответствующего ограничения нет.
+type Limits struct {
+	// Timeout ограничивает общее время работы всех команд джоба.
+	Timeout time.Duration
+
+	// Memory ограничивает объём памяти в байтах, который может занять каждая команда джоба.
+	Memory int64
+
+	// CPUTime ограничивает процессорное время, которое может потратить каждая команда джоба.
+	CPUTime time.Duration
 }
 
 // Cmd описывает одну команду сборки.
@@ -66,5 +92,12 @@ type Cmd struct {
 type Graph struct {
 	SourceFiles map[ID]string
 
+	// SourceCopies перечисляет дополнительные пути к исходным файлам, содержимое которых уже есть
+	// в SourceFiles под другим путём. Так одному ID может соответствовать несколько путей, например,
+	// у пустых файлов или одинаковых testdata.
+	//
+	// Ключом служит путь, значением - ID из SourceFiles.
+	SourceCopies map[string]ID
+
 	Jobs []Job
 }
@@ -0,0 +1,222 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileID returns content ID of the file.
+//
+// The ID is sha1 of the file content, the same hash that is used to verify file uploads.
+func FileID(path string) (ID, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ID{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ID{}, err
+	}
+
+	var id ID
+	copy(id[:], h.Sum(nil))
+	return id, nil
+}
+
+// ComputeJobIDs replaces job IDs in the graph with deterministic hashes.
+//
+// Graph.SourceFiles is rebuilt from Job.Inputs of all jobs. Files are read from sourceDir
+// and keyed by their FileID. When several inputs have identical content, the first one goes
+// to SourceFiles and the rest to Graph.SourceCopies.
+//
+// Job ID is computed from the commands rendered against a canonical context, content of the inputs,
+// Limits, Requires and IDs of the dependencies. Job name does not affect the ID. References to dependencies
+// in Deps and in Cmd templates are rewritten to the new IDs.
+//
+// Jobs that differ only by name would get the same ID, so ComputeJobIDs returns an error wrapping
+// ErrDuplicateID that names both jobs.
+func ComputeJobIDs(graph *Graph, sourceDir string) error {
+	sources := map[string]ID{}
+	graph.SourceFiles = map[ID]string{}
+	graph.SourceCopies = nil
+
+	for _, job := range graph.Jobs {
+		for _, input := range job.Inputs {
+			path := filepath.ToSlash(filepath.Clean(input))
+			if _, ok := sources[path]; ok {
+				continue
+			}
+
+			id, err := FileID(filepath.Join(sourceDir, path))
+			if err != nil {
+				return fmt.Errorf("job %q: %w", job.Name, err)
+			}
+
+			sources[path] = id
+			if _, ok := graph.SourceFiles[id]; !ok {
+				graph.SourceFiles[id] = path
+				continue
+			}
+
+			if graph.SourceCopies == nil {
+				graph.SourceCopies = map[string]ID{}
+			}
+			graph.SourceCopies[path] = id
+		}
+	}
+
+	if err := Validate(*graph); err != nil {
+		return err
+	}
+
+	// Graph may share slices with the caller, so everything that is rewritten below is copied first.
+	graph.Jobs = slices.Clone(graph.Jobs)
+
+	index := map[ID]int{}
+	for i := range graph.Jobs {
+		job := &graph.Jobs[i]
+		job.Deps = slices.Clone(job.Deps)
+		job.Cmds = slices.Clone(job.Cmds)
+		for k := range job.Cmds {
+			job.Cmds[k].Exec = slices.Clone(job.Cmds[k].Exec)
+			job.Cmds[k].Environ = slices.Clone(job.Cmds[k].Environ)
+		}
+
+		index[job.ID] = i
+	}
+
+	newIDs := map[ID]ID{}
+	owners := map[ID]*Job{}
+	for _, job := range TopSort(graph.Jobs) {
+		j := &graph.Jobs[index[job.ID]]
+
+		for i, dep := range j.Deps {
+			j.Deps[i] = newIDs[dep]
+			for k := range j.Cmds {
+				replaceID(&j.Cmds[k], dep, newIDs[dep])
+			}
+		}
+
+		id, err := hashJob(j, sources)
+		if err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+
+		if prev, ok := owners[id]; ok {
+			return fmt.Errorf("%w %s: jobs %q and %q differ only by name", ErrDuplicateID, id, prev.Name, j.Name)
+		}
+		owners[id] = j
+
+		newIDs[job.ID] = id
+		j.ID = id
+	}
+
+	return nil
+}
+
+func replaceID(cmd *Cmd, from, to ID) {
+	replace := func(s *string) {
+		*s = strings.ReplaceAll(*s, from.String(), to.String())
+	}
+
+	for i := range cmd.Exec {
+		replace(&cmd.Exec[i])
+	}
+	for i := range cmd.Environ {
+		replace(&cmd.Environ[i])
+	}
+	replace(&cmd.WorkingDirectory)
+	replace(&cmd.CatTemplate)
+	replace(&cmd.CatOutput)
+}
+
+func hashJob(job *Job, sources map[string]ID) (ID, error) {
+	h := sha1.New()
+
+	ctx := JobContext{
+		SourceDir: "/source",
+		OutputDir: "/output",
+		Deps:      map[ID]string{},
+	}
+
+	deps := slices.Clone(job.Deps)
+	slices.SortFunc(deps, func(a, b ID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+
+	writeInt(h, int64(len(deps)))
+	for _, dep := range deps {
+		ctx.Deps[dep] = "/deps/" + dep.String()
+		_, _ = h.Write(dep[:])
+	}
+
+	inputs := make([]string, 0, len(job.Inputs))
+	for _, input := range job.Inputs {
+		inputs = append(inputs, filepath.ToSlash(filepath.Clean(input)))
+	}
+	slices.Sort(inputs)
+
+	writeInt(h, int64(len(inputs)))
+	for _, input := range inputs {
+		id := sources[input]
+		writeString(h, input)
+		_, _ = h.Write(id[:])
+	}
+
+	writeInt(h, int64(len(job.Cmds)))
+	for i := range job.Cmds {
+		cmd, err := job.Cmds[i].Render(ctx)
+		if err != nil {
+			return ID{}, err
+		}
+
+		writeStrings(h, cmd.Exec)
+		writeStrings(h, cmd.Environ)
+		writeString(h, cmd.WorkingDirectory)
+		writeString(h, cmd.CatTemplate)
+		writeString(h, cmd.CatOutput)
+	}
+
+	writeInt(h, int64(job.Limits.Timeout))
+	writeInt(h, job.Limits.Memory)
+	writeInt(h, int64(job.Limits.CPUTime))
+
+	keys := slices.Sorted(maps.Keys(job.Requires))
+	writeInt(h, int64(len(keys)))
+	for _, key := range keys {
+		writeString(h, key)
+		writeString(h, job.Requires[key])
+	}
+
+	var id ID
+	copy(id[:], h.Sum(nil))
+	return id, nil
+}
+
+func writeInt(h hash.Hash, v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	_, _ = h.Write(buf[:binary.PutVarint(buf[:], v)])
+}
+
+func writeString(h hash.Hash, s string) {
+	writeInt(h, int64(len(s)))
+	_, _ = io.WriteString(h, s)
+}
+
+func writeStrings(h hash.Hash, l []string) {
+	writeInt(h, int64(len(l)))
+	for _, s := range l {
+		writeString(h, s)
+	}
+}
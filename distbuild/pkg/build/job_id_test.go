@@ -0,0 +1,137 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testGraph(a, b ID, name string) Graph {
+	return Graph{
+		Jobs: []Job{
+			{
+				ID:   b,
+				Name: name + " cat",
+				Deps: []ID{a},
+				Cmds: []Cmd{{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", a)}}},
+			},
+			{
+				ID:     a,
+				Name:   name + " write",
+				Inputs: []string{"a.txt"},
+				Cmds:   []Cmd{{Exec: []string{"cp", "{{.SourceDir}}/a.txt", "{{.OutputDir}}/out.txt"}}},
+			},
+		},
+	}
+}
+
+func TestComputeJobIDs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0666))
+
+	orig := testGraph(ID{'a'}, ID{'b'}, "first")
+	first := orig
+	require.NoError(t, ComputeJobIDs(&first, dir))
+
+	require.Equal(t, ID{'b'}, orig.Jobs[0].ID)
+	require.Equal(t, ID{'a'}, orig.Jobs[0].Deps[0])
+
+	fileID, err := FileID(filepath.Join(dir, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, map[ID]string{fileID: "a.txt"}, first.SourceFiles)
+
+	second := testGraph(ID{'x'}, ID{'y'}, "second")
+	require.NoError(t, ComputeJobIDs(&second, dir))
+
+	require.Equal(t, first.Jobs[0].ID, second.Jobs[0].ID)
+	require.Equal(t, first.Jobs[1].ID, second.Jobs[1].ID)
+	require.NotEqual(t, first.Jobs[0].ID, first.Jobs[1].ID)
+	require.Equal(t, []ID{first.Jobs[1].ID}, first.Jobs[0].Deps)
+
+	cmd, err := first.Jobs[0].Cmds[0].Render(JobContext{Deps: map[ID]string{first.Jobs[1].ID: "/a"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "/a/out.txt"}, cmd.Exec)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("bar"), 0666))
+
+	third := testGraph(ID{'a'}, ID{'b'}, "first")
+	require.NoError(t, ComputeJobIDs(&third, dir))
+	require.NotEqual(t, first.Jobs[0].ID, third.Jobs[0].ID)
+	require.NotEqual(t, first.Jobs[1].ID, third.Jobs[1].ID)
+}
+
+func TestComputeJobIDsLimits(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0666))
+
+	first := testGraph(ID{'a'}, ID{'b'}, "")
+	require.NoError(t, ComputeJobIDs(&first, dir))
+
+	second := testGraph(ID{'a'}, ID{'b'}, "")
+	second.Jobs[1].Limits.Memory = 1 << 20
+	require.NoError(t, ComputeJobIDs(&second, dir))
+
+	require.NotEqual(t, first.Jobs[1].ID, second.Jobs[1].ID)
+}
+
+func TestComputeJobIDsRequires(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0666))
+
+	first := testGraph(ID{'a'}, ID{'b'}, "")
+	require.NoError(t, ComputeJobIDs(&first, dir))
+
+	arm := testGraph(ID{'a'}, ID{'b'}, "")
+	arm.Jobs[1].Requires = Labels{LabelArch: "arm64"}
+	require.NoError(t, ComputeJobIDs(&arm, dir))
+	require.NotEqual(t, first.Jobs[1].ID, arm.Jobs[1].ID)
+
+	amd := testGraph(ID{'a'}, ID{'b'}, "")
+	amd.Jobs[1].Requires = Labels{LabelArch: "amd64"}
+	require.NoError(t, ComputeJobIDs(&amd, dir))
+	require.NotEqual(t, arm.Jobs[1].ID, amd.Jobs[1].ID)
+}
+
+func TestComputeJobIDsDuplicateSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "testdata"), 0777))
+	for _, name := range []string{"a.txt", "b.txt", "testdata/a.golden", "testdata/b.golden"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0666))
+	}
+
+	graph := Graph{Jobs: []Job{
+		{ID: ID{'a'}, Inputs: []string{"a.txt", "b.txt"}},
+		{ID: ID{'b'}, Inputs: []string{"testdata/a.golden", "testdata/b.golden", "a.txt"}},
+	}}
+	require.NoError(t, ComputeJobIDs(&graph, dir))
+	require.NoError(t, Validate(graph))
+
+	empty, err := FileID(filepath.Join(dir, "a.txt"))
+	require.NoError(t, err)
+
+	require.Equal(t, map[ID]string{empty: "a.txt"}, graph.SourceFiles)
+	require.Equal(t, map[string]ID{
+		"b.txt":             empty,
+		"testdata/a.golden": empty,
+		"testdata/b.golden": empty,
+	}, graph.SourceCopies)
+	require.NotEqual(t, graph.Jobs[0].ID, graph.Jobs[1].ID)
+}
+
+func TestComputeJobIDsDuplicateJob(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0666))
+
+	graph := testGraph(ID{'a'}, ID{'b'}, "")
+	copied := graph.Jobs[1]
+	copied.ID = ID{'c'}
+	copied.Name = "copy"
+	graph.Jobs = append(graph.Jobs, copied)
+
+	err := ComputeJobIDs(&graph, dir)
+	require.ErrorIs(t, err, ErrDuplicateID)
+	require.Contains(t, err.Error(), `" write" and "copy"`)
+}
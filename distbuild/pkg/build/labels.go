@@ -0,0 +1,67 @@
+package build
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Well-known labels that every worker reports.
+const (
+	LabelOS   = "os"
+	LabelArch = "arch"
+)
+
+// Labels describe properties of a worker: OS, architecture, tool versions and arbitrary tags.
+//
+// Jobs list required labels in Job.Requires.
+type Labels map[string]string
+
+// HostLabels returns os and arch labels of the current process.
+func HostLabels() Labels {
+	return Labels{
+		LabelOS:   runtime.GOOS,
+		LabelArch: runtime.GOARCH,
+	}
+}
+
+// ParseLabels parses labels in key=value form, as passed on the command line.
+func ParseLabels(list []string) (Labels, error) {
+	labels := Labels{}
+	for _, kv := range list {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", kv)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// Satisfies reports whether l has every label from required with the same value.
+//
+// Empty required is satisfied by any labels.
+func (l Labels) Satisfies(required Labels) bool {
+	for key, value := range required {
+		if v, ok := l[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats labels as a sorted comma-separated list of key=value pairs.
+func (l Labels) String() string {
+	var b strings.Builder
+	for i, key := range slices.Sorted(maps.Keys(l)) {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(l[key])
+	}
+	return b.String()
+}
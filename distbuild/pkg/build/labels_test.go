@@ -0,0 +1,34 @@
+package build
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabels(t *testing.T) {
+	worker := Labels{LabelOS: "linux", LabelArch: "arm64", "go": "go1.22.1", "gpu": ""}
+
+	require.True(t, worker.Satisfies(nil))
+	require.True(t, worker.Satisfies(Labels{LabelArch: "arm64"}))
+	require.True(t, worker.Satisfies(Labels{"go": "go1.22.1", "gpu": ""}))
+	require.False(t, worker.Satisfies(Labels{LabelArch: "amd64"}))
+	require.False(t, worker.Satisfies(Labels{"rust": ""}))
+	require.False(t, Labels(nil).Satisfies(Labels{LabelOS: "linux"}))
+
+	require.Equal(t, "arch=arm64,go=go1.22.1,gpu=,os=linux", worker.String())
+	require.Equal(t, runtime.GOARCH, HostLabels()[LabelArch])
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"go=go1.22.1", "gpu=", "zone=a=b"})
+	require.NoError(t, err)
+	require.Equal(t, Labels{"go": "go1.22.1", "gpu": "", "zone": "a=b"}, labels)
+
+	_, err = ParseLabels([]string{"gpu"})
+	require.Error(t, err)
+
+	_, err = ParseLabels([]string{"=x"})
+	require.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package build
 
 // TopSort sorts jobs in topological order assuming dependency graph contains no cycles.
+//
+// Use Validate to check the graph before sorting it.
 func TopSort(jobs []Job) []Job {
 	var sorted []Job
 	visited := make([]bool, len(jobs))
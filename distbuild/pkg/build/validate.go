@@ -0,0 +1,150 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrCycle        = errors.New("dependency cycle")
+	ErrDuplicateID  = errors.New("duplicate job id")
+	ErrDanglingDep  = errors.New("dependency on unknown job")
+	ErrMissingInput = errors.New("input is missing from source files")
+	ErrInvalidCmd   = errors.New("invalid cmd")
+)
+
+// Validate checks that graph is well-formed.
+//
+// The returned error joins all problems found in the graph. Each of them wraps
+// one of ErrCycle, ErrDuplicateID, ErrDanglingDep, ErrMissingInput or ErrInvalidCmd.
+func Validate(graph Graph) error {
+	var errs []error
+
+	sources := map[string]struct{}{}
+	for _, path := range graph.SourceFiles {
+		sources[filepath.Clean(path)] = struct{}{}
+	}
+	for path, id := range graph.SourceCopies {
+		if _, ok := graph.SourceFiles[id]; !ok {
+			errs = append(errs, fmt.Errorf("%w: copy %s refers to unknown file %s", ErrMissingInput, path, id))
+			continue
+		}
+		sources[filepath.Clean(path)] = struct{}{}
+	}
+
+	jobs := map[ID]*Job{}
+	for i := range graph.Jobs {
+		job := &graph.Jobs[i]
+
+		if prev, ok := jobs[job.ID]; ok {
+			errs = append(errs, fmt.Errorf("%w %s: used by %q and %q", ErrDuplicateID, job.ID, prev.Name, job.Name))
+			continue
+		}
+		jobs[job.ID] = job
+	}
+
+	for i := range graph.Jobs {
+		job := &graph.Jobs[i]
+
+		for _, dep := range job.Deps {
+			if _, ok := jobs[dep]; !ok {
+				errs = append(errs, fmt.Errorf("job %q: %w %s", job.Name, ErrDanglingDep, dep))
+			}
+		}
+
+		for _, input := range job.Inputs {
+			if _, ok := sources[filepath.Clean(input)]; !ok {
+				errs = append(errs, fmt.Errorf("job %q: %w: %s", job.Name, ErrMissingInput, input))
+			}
+		}
+
+		if err := dryRun(job); err != nil {
+			errs = append(errs, fmt.Errorf("job %q: %w: %w", job.Name, ErrInvalidCmd, err))
+		}
+	}
+
+	errs = append(errs, findCycles(graph.Jobs, jobs)...)
+	return errors.Join(errs...)
+}
+
+// dryRun renders all commands of the job against a fake context.
+func dryRun(job *Job) error {
+	ctx := JobContext{
+		SourceDir: "/source",
+		OutputDir: "/output",
+		Deps:      map[ID]string{},
+	}
+
+	for _, dep := range job.Deps {
+		ctx.Deps[dep] = filepath.Join("/deps", dep.String())
+	}
+
+	for i := range job.Cmds {
+		if _, err := job.Cmds[i].Render(ctx); err != nil {
+			return fmt.Errorf("cmd #%d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// findCycles reports every back edge found by depth-first search as a separate cycle.
+func findCycles(order []Job, jobs map[ID]*Job) []error {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	var errs []error
+	state := map[ID]int{}
+
+	var stack []*Job
+	var visit func(job *Job)
+	visit = func(job *Job) {
+		state[job.ID] = inProgress
+		stack = append(stack, job)
+
+		for _, dep := range job.Deps {
+			next, ok := jobs[dep]
+			if !ok {
+				continue
+			}
+
+			switch state[dep] {
+			case unvisited:
+				visit(next)
+			case inProgress:
+				errs = append(errs, cycleError(stack, next))
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[job.ID] = done
+	}
+
+	for i := range order {
+		if state[order[i].ID] == unvisited {
+			visit(jobs[order[i].ID])
+		}
+	}
+
+	return errs
+}
+
+func cycleError(stack []*Job, start *Job) error {
+	var names []string
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].ID == start.ID {
+			for _, job := range stack[i:] {
+				names = append(names, fmt.Sprintf("%q", job.Name))
+			}
+			break
+		}
+	}
+
+	names = append(names, fmt.Sprintf("%q", start.Name))
+	return fmt.Errorf("%w: %s", ErrCycle, strings.Join(names, " -> "))
+}
@@ -0,0 +1,85 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	graph := Graph{
+		SourceFiles: map[ID]string{{'s'}: "a.txt"},
+		Jobs: []Job{
+			{
+				ID:     ID{'a'},
+				Name:   "write",
+				Inputs: []string{"./a.txt"},
+				Cmds:   []Cmd{{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"}},
+			},
+			{
+				ID:   ID{'b'},
+				Name: "cat",
+				Deps: []ID{{'a'}},
+				Cmds: []Cmd{{Exec: []string{"cat", `{{index .Deps "6100000000000000000000000000000000000000"}}/out.txt`}}},
+			},
+		},
+	}
+
+	require.NoError(t, Validate(graph))
+}
+
+func TestValidateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		jobs   []Job
+		target error
+		msg    string
+	}{
+		{
+			name: "cycle",
+			jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Deps: []ID{{'c'}}},
+				{ID: ID{'b'}, Name: "b", Deps: []ID{{'a'}}},
+				{ID: ID{'c'}, Name: "c", Deps: []ID{{'b'}}},
+			},
+			target: ErrCycle,
+			msg:    `"a" -> "c" -> "b" -> "a"`,
+		},
+		{
+			name:   "self loop",
+			jobs:   []Job{{ID: ID{'a'}, Name: "a", Deps: []ID{{'a'}}}},
+			target: ErrCycle,
+			msg:    `"a" -> "a"`,
+		},
+		{
+			name:   "dangling dep",
+			jobs:   []Job{{ID: ID{'a'}, Name: "a", Deps: []ID{{'x'}}}},
+			target: ErrDanglingDep,
+			msg:    ID{'x'}.String(),
+		},
+		{
+			name:   "duplicate id",
+			jobs:   []Job{{ID: ID{'a'}, Name: "a"}, {ID: ID{'a'}, Name: "b"}},
+			target: ErrDuplicateID,
+			msg:    `"a" and "b"`,
+		},
+		{
+			name:   "missing input",
+			jobs:   []Job{{ID: ID{'a'}, Name: "a", Inputs: []string{"b.txt"}}},
+			target: ErrMissingInput,
+			msg:    "b.txt",
+		},
+		{
+			name:   "bad template",
+			jobs:   []Job{{ID: ID{'a'}, Name: "a", Cmds: []Cmd{{Exec: []string{"echo", "{{.Unknown}}"}}}}},
+			target: ErrInvalidCmd,
+			msg:    `job "a"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(Graph{SourceFiles: map[ID]string{{'s'}: "a.txt"}, Jobs: tc.jobs})
+			require.ErrorIs(t, err, tc.target)
+			require.Contains(t, err.Error(), tc.msg)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package chunker
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Chunk описывает один кусок файла.
+type Chunk struct {
+	// ID равен sha1 содержимого куска.
+	ID build.ID
+
+	Offset int64
+	Size   int64
+}
+
+// Config задаёт ограничения на размер кусков.
+type Config struct {
+	Min, Avg, Max int
+}
+
+// DefaultConfig подобран для исходников и сгенерированных файлов размером от сотни килобайт.
+var DefaultConfig = Config{
+	Min: 16 << 10,
+	Avg: 64 << 10,
+	Max: 256 << 10,
+}
+
+var errInvalidConfig = errors.New("chunker: invalid config")
+
+var gear = func() (table [256]uint64) {
+	for i := range table {
+		sum := sha1.Sum([]byte{byte(i)})
+		table[i] = binary.LittleEndian.Uint64(sum[:8])
+	}
+	return
+}()
+
+// Split режет содержимое r на куски, границы которых определяются самим содержимым.
+//
+// Граница куска ставится там, где rolling hash последних байт обнуляется по маске. Поэтому вставка
+// или удаление байт в середине файла меняет только соседние куски, а остальные куски сохраняют свои ID.
+func Split(r io.Reader, config Config) ([]Chunk, error) {
+	if config.Min <= 0 || config.Min > config.Avg || config.Avg > config.Max {
+		return nil, errInvalidConfig
+	}
+
+	mask := uint64(1)<<(bits.Len(uint(config.Avg))-1) - 1
+
+	var chunks []Chunk
+	var offset int64
+
+	br := bufio.NewReader(r)
+	buf := make([]byte, 0, config.Max)
+
+	var hash uint64
+
+	cut := func() {
+		chunks = append(chunks, Chunk{ID: sha1.Sum(buf), Offset: offset, Size: int64(len(buf))})
+		offset += int64(len(buf))
+
+		buf = buf[:0]
+		hash = 0
+	}
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, b)
+		hash = hash<<1 + gear[b]
+
+		if len(buf) >= config.Min && hash&mask == 0 || len(buf) >= config.Max {
+			cut()
+		}
+	}
+
+	if len(buf) != 0 || len(chunks) == 0 {
+		cut()
+	}
+
+	return chunks, nil
+}
+
+// IDs возвращает ID кусков в том же порядке.
+func IDs(chunks []Chunk) []build.ID {
+	ids := make([]build.ID, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.ID
+	}
+	return ids
+}
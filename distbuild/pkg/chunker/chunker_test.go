@@ -0,0 +1,80 @@
+package chunker_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/chunker"
+)
+
+var testConfig = chunker.Config{Min: 256, Avg: 1024, Max: 4096}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.New(rand.NewSource(42)).Read(data)
+	return data
+}
+
+func TestSplit(t *testing.T) {
+	data := randomData(1 << 20)
+
+	chunks, err := chunker.Split(bytes.NewReader(data), testConfig)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 100)
+
+	var offset int64
+	for _, c := range chunks {
+		require.Equal(t, offset, c.Offset)
+		require.LessOrEqual(t, c.Size, int64(testConfig.Max))
+
+		part := data[c.Offset : c.Offset+c.Size]
+		require.Equal(t, build.ID(sha1.Sum(part)), c.ID)
+
+		offset += c.Size
+	}
+	require.Equal(t, int64(len(data)), offset)
+}
+
+func TestSplitEmpty(t *testing.T) {
+	chunks, err := chunker.Split(bytes.NewReader(nil), testConfig)
+	require.NoError(t, err)
+	require.Equal(t, []chunker.Chunk{{ID: sha1.Sum(nil)}}, chunks)
+}
+
+func TestSplitInsertion(t *testing.T) {
+	data := randomData(1 << 20)
+
+	edited := append([]byte(nil), data[:len(data)/2]...)
+	edited = append(edited, "// generated code changed here\n"...)
+	edited = append(edited, data[len(data)/2:]...)
+
+	before, err := chunker.Split(bytes.NewReader(data), testConfig)
+	require.NoError(t, err)
+
+	after, err := chunker.Split(bytes.NewReader(edited), testConfig)
+	require.NoError(t, err)
+
+	known := map[build.ID]bool{}
+	for _, id := range chunker.IDs(before) {
+		known[id] = true
+	}
+
+	changed := 0
+	for _, id := range chunker.IDs(after) {
+		if !known[id] {
+			changed++
+		}
+	}
+
+	require.LessOrEqual(t, changed, 2)
+}
+
+func TestSplitInvalidConfig(t *testing.T) {
+	_, err := chunker.Split(bytes.NewReader(nil), chunker.Config{Min: 10, Avg: 5, Max: 20})
+	require.Error(t, err)
+}
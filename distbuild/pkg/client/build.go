@@ -7,12 +7,33 @@ import (
 
 	"go.uber.org/zap"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
 type Client struct {
 }
 
+// Config задаёт необязательные настройки клиента.
+type Config struct {
+	// User передаётся координатору в api.BuildRequest.User.
+	User string
+
+	// GRPCEndpoint задаёт адрес gRPC сервера координатора в виде host:port.
+	//
+	// Если GRPCEndpoint не пустой, клиент запускает сборку и следит за ней через api.GRPCBuildClient.
+	// Файлы по-прежнему загружаются по HTTP на apiEndpoint. Соединение открывается через api.DialGRPC
+	// на время одного вызова Build или Attach.
+	GRPCEndpoint string
+
+	// Auth задаёт токен и сертификат CA, с которыми клиент ходит в координатор.
+	//
+	// Все запросы клиент посылает через auth.Authenticator.HTTPClient или с опциями
+	// из auth.Authenticator.GRPCDialOptions.
+	Auth auth.Config
+}
+
 func NewClient(
 	l *zap.Logger,
 	apiEndpoint string,
@@ -21,6 +42,15 @@ func NewClient(
 	panic("implement me")
 }
 
+func NewClientWithConfig(
+	l *zap.Logger,
+	apiEndpoint string,
+	sourceDir string,
+	config Config,
+) *Client {
+	panic("implement me")
+}
+
 type BuildListener interface {
 	OnJobStdout(jobID build.ID, stdout []byte) error
 	OnJobStderr(jobID build.ID, stderr []byte) error
@@ -29,6 +59,44 @@ type BuildListener interface {
 	OnJobFailed(jobID build.ID, code int, error string) error
 }
 
+// FailureReasonListener может дополнительно реализовать BuildListener, чтобы узнавать, почему джоб упал.
+//
+// Для упавшего джоба клиент вызывает OnJobFailedWithReason вместо OnJobFailed. reason совпадает
+// с api.JobResult.Reason и позволяет отличить нарушение build.Limits от обычной ошибки команды.
+type FailureReasonListener interface {
+	OnJobFailedWithReason(jobID build.ID, code int, reason api.FailureReason, error string) error
+}
+
+// CacheListener может дополнительно реализовать BuildListener, чтобы узнавать о джобах,
+// которые не запускались, потому что их результат нашёлся в кеше.
+//
+// Клиент вызывает OnJobCached для JobResult с выставленным Cached перед тем, как передать
+// вывод джоба и вызвать OnJobFinished.
+type CacheListener interface {
+	OnJobCached(jobID build.ID) error
+}
+
 func (c *Client) Build(ctx context.Context, graph build.Graph, lsn BuildListener) error {
 	panic("implement me")
 }
+
+// BuildWithOutputs работает так же, как Build, но после BuildFinished скачивает результаты джобов outputs.
+//
+// Артефакт каждого джоба из outputs распаковывается в директорию outputDir/<job id>. Артефакты скачиваются
+// с координатора по протоколу пакета artifact, а распаковываются через tarstream.Receive.
+func (c *Client) BuildWithOutputs(
+	ctx context.Context,
+	graph build.Graph,
+	outputs []build.ID,
+	outputDir string,
+	lsn BuildListener,
+) error {
+	panic("implement me")
+}
+
+// Attach подключается к уже запущенной сборке и дожидается её завершения.
+//
+// lsn получит все события сборки, в том числе те, что произошли до вызова Attach.
+func (c *Client) Attach(ctx context.Context, buildID build.ID, lsn BuildListener) error {
+	panic("implement me")
+}
@@ -0,0 +1,127 @@
+package compression
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrChecksumMismatch = errors.New("content checksum does not match id")
+
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+)
+
+// AcceptEncoding перечисляет кодирования, которые понимают компоненты distbuild.
+//
+// Клиент должен явно выставить этот заголовок. Иначе http.Transport сам допишет
+// Accept-Encoding: gzip и прозрачно распакует ответ, скрыв Content-Encoding от вызывающего кода.
+const AcceptEncoding = Gzip
+
+// Negotiate выбирает кодирование ответа по значению заголовка Accept-Encoding.
+//
+// Если клиент не поддерживает ни одно сжатие, Negotiate возвращает Identity. Как в RFC 9110, раздел 12.5.3,
+// "*" относится ко всем кодированиям, которые не перечислены в заголовке явно.
+func Negotiate(acceptEncoding string) string {
+	gzipQ, anyQ := -1.0, -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		switch name {
+		case Gzip:
+			gzipQ = max(gzipQ, q)
+		case "*":
+			anyQ = max(anyQ, q)
+		}
+	}
+
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+
+	if gzipQ > 0 {
+		return Gzip
+	}
+	return Identity
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewWriter возвращает writer, сжимающий данные кодированием encoding.
+//
+// Close не закрывает w, но дописывает в него хвост сжатого потока.
+func NewWriter(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case "", Identity:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// NewReader возвращает reader, распаковывающий поток r, сжатый кодированием encoding.
+func NewReader(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "", Identity:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+type verifyReader struct {
+	r  io.Reader
+	h  hash.Hash
+	id build.ID
+}
+
+// VerifyReader проверяет, что sha1 прочитанных из r данных совпадает с id.
+//
+// Если хеш не совпал, вместо io.EOF чтение вернёт ErrChecksumMismatch.
+func VerifyReader(r io.Reader, id build.ID) io.Reader {
+	return &verifyReader{r: r, h: sha1.New(), id: id}
+}
+
+func (v *verifyReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+
+	if err == io.EOF {
+		var sum build.ID
+		copy(sum[:], v.h.Sum(nil))
+
+		if sum != v.id {
+			return n, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, v.id, sum)
+		}
+	}
+
+	return n, err
+}
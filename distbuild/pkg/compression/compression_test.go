@@ -0,0 +1,80 @@
+package compression_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/compression"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		header   string
+		expected string
+	}{
+		{"", compression.Identity},
+		{"gzip", compression.Gzip},
+		{"br, gzip;q=0.5", compression.Gzip},
+		{"GZIP", compression.Gzip},
+		{"gzip;q=0", compression.Identity},
+		{"deflate, br", compression.Identity},
+		{"gzip;q=abc", compression.Identity},
+		{"*", compression.Gzip},
+		{"br, *;q=0.1", compression.Gzip},
+		{"*;q=0", compression.Identity},
+		{"gzip;q=0, *", compression.Identity},
+		{"*;q=0, gzip", compression.Gzip},
+	} {
+		t.Run(tc.header, func(t *testing.T) {
+			require.Equal(t, tc.expected, compression.Negotiate(tc.header))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("package main\n"), 1024)
+
+	for _, encoding := range []string{compression.Identity, compression.Gzip} {
+		t.Run(encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := compression.NewWriter(&buf, encoding)
+			require.NoError(t, err)
+			_, err = w.Write(content)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			if encoding == compression.Gzip {
+				require.Less(t, buf.Len(), len(content)/10)
+			}
+
+			r, err := compression.NewReader(&buf, encoding)
+			require.NoError(t, err)
+			defer r.Close()
+
+			decoded, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, content, decoded)
+		})
+	}
+
+	_, err := compression.NewWriter(io.Discard, "br")
+	require.Error(t, err)
+}
+
+func TestVerifyReader(t *testing.T) {
+	content := []byte("foobar")
+	id := build.ID(sha1.Sum(content))
+
+	b, err := io.ReadAll(compression.VerifyReader(bytes.NewReader(content), id))
+	require.NoError(t, err)
+	require.Equal(t, content, b)
+
+	_, err = io.ReadAll(compression.VerifyReader(bytes.NewReader([]byte("foobaz")), id))
+	require.ErrorIs(t, err, compression.ErrChecksumMismatch)
+}
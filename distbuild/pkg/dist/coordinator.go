@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
 	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
 )
@@ -20,6 +22,41 @@ var defaultConfig = scheduler.Config{
 	DepsTimeout:  time.Millisecond * 100,
 }
 
+// Config задаёт необязательные настройки координатора.
+type Config struct {
+	// RootDir задаёт директорию для журнала. Если RootDir не пустой, координатор
+	// работает так же, как созданный через NewPersistentCoordinator.
+	RootDir string
+
+	// WorkerTimeout задаёт, через сколько времени без heartbeat-ов воркер считается мёртвым.
+	//
+	// Нулевое значение означает DefaultWorkerTimeout.
+	WorkerTimeout time.Duration
+
+	// LabelsGrace задаёт, сколько времени после запуска координатор не отвергает сборки, для джобов
+	// которых нет подходящего воркера, и передаётся в NewWorkerLabels.
+	//
+	// Нулевое значение означает WorkerTimeout. Отрицательное значение выключает задержку.
+	LabelsGrace time.Duration
+
+	// MaxJobsPerUser передаётся в scheduler.Config.MaxJobsPerUser.
+	MaxJobsPerUser int
+
+	// Auth включает проверку входящих запросов.
+	//
+	// ServeHTTP пропускает все запросы через auth.Authenticator.Middleware, а gRPC сервер, переданный
+	// в RegisterGRPC, должен быть создан с опциями из auth.Authenticator.GRPCServerOptions. Heartbeat
+	// отвергает запрос, если auth.FromContext(ctx).VerifyWorker(string(req.WorkerID)) вернул ошибку.
+	// К воркерам координатор ходит через auth.Authenticator.HTTPClient. Authenticator создаётся через
+	// auth.NewServer, поэтому CA и токены без сертификата координатора - ошибка.
+	Auth auth.Config
+
+	// MaxEventBuilds задаёт, для скольких последних сборок координатор хранит журнал событий.
+	//
+	// Нулевое значение означает events.DefaultMaxBuilds.
+	MaxEventBuilds int
+}
+
 func NewCoordinator(
 	log *zap.Logger,
 	fileCache *filecache.Cache,
@@ -27,8 +64,37 @@ func NewCoordinator(
 	panic("implement me")
 }
 
+// NewPersistentCoordinator создаёт координатора, который записывает изменения своего состояния
+// в журнал внутри rootDir.
+//
+// Если журнал уже существует, координатор продолжает исполнение незавершённых сборок,
+// восстановленных функцией journal.Open. Воркеры заново регистрируются через heartbeat, а клиенты могут переподключиться к сборке по её build.ID.
+func NewPersistentCoordinator(
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	rootDir string,
+) (*Coordinator, error) {
+	panic("implement me")
+}
+
+func NewCoordinatorWithConfig(
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	config Config,
+) (*Coordinator, error) {
+	panic("implement me")
+}
+
 func (c *Coordinator) Stop() {}
 
 func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
+
+// RegisterGRPC регистрирует на s gRPC версию api.Service и api.HeartbeatService координатора.
+//
+// Используйте api.NewGRPCBuildHandler и api.NewGRPCHeartbeatHandler. Файлы и артефакты по-прежнему
+// передаются по HTTP через ServeHTTP.
+func (c *Coordinator) RegisterGRPC(s grpc.ServiceRegistrar) {
+	panic("implement me")
+}
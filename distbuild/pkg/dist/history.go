@@ -0,0 +1,55 @@
+package dist
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// DefaultJobDuration используется как оценка длительности джоба, про который ещё ничего не известно.
+const DefaultJobDuration = time.Second
+
+// DurationHistory запоминает, сколько выполнялись джобы с каждым именем.
+//
+// ID джоба меняется при любом изменении входных файлов, а имя остаётся прежним. Поэтому история
+// ведётся по build.Job.Name.
+type DurationHistory struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+func NewDurationHistory() *DurationHistory {
+	return &DurationHistory{durations: make(map[string]time.Duration)}
+}
+
+// Observe учитывает очередную длительность джоба.
+//
+// Оценка считается как экспоненциальное скользящее среднее, так что старые запуски постепенно забываются.
+func (h *DurationHistory) Observe(name string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if prev, ok := h.durations[name]; ok {
+		d = (prev + d) / 2
+	}
+
+	h.durations[name] = d
+}
+
+// Estimate возвращает оценку длительности джоба.
+func (h *DurationHistory) Estimate(job *build.Job) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if d, ok := h.durations[job.Name]; ok {
+		return d
+	}
+
+	return DefaultJobDuration
+}
+
+// Priorities возвращает приоритеты джобов графа для Scheduler.ScheduleJobWithPriority.
+func (h *DurationHistory) Priorities(graph *build.Graph) map[build.ID]time.Duration {
+	return build.CriticalPath(graph.Jobs, h.Estimate)
+}
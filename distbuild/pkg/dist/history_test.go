@@ -0,0 +1,37 @@
+package dist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+)
+
+func TestDurationHistory(t *testing.T) {
+	h := dist.NewDurationHistory()
+
+	graph := &build.Graph{
+		Jobs: []build.Job{
+			{ID: build.ID{'a'}, Name: "compile"},
+			{ID: build.ID{'b'}, Name: "link", Deps: []build.ID{{'a'}}},
+			{ID: build.ID{'c'}, Name: "vet"},
+		},
+	}
+
+	require.Equal(t, dist.DefaultJobDuration, h.Estimate(&graph.Jobs[0]))
+
+	h.Observe("compile", 10*time.Second)
+	h.Observe("compile", 20*time.Second)
+	h.Observe("vet", 2*time.Second)
+
+	require.Equal(t, 15*time.Second, h.Estimate(&graph.Jobs[0]))
+
+	require.Equal(t, map[build.ID]time.Duration{
+		{'a'}: 15*time.Second + dist.DefaultJobDuration,
+		{'b'}: dist.DefaultJobDuration,
+		{'c'}: 2 * time.Second,
+	}, h.Priorities(graph))
+}
@@ -0,0 +1,89 @@
+package dist
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrNoMatchingWorker = errors.New("no live worker satisfies job requirements")
+
+// WorkerLabels хранит метки живых воркеров.
+type WorkerLabels struct {
+	now      func() time.Time
+	deadline time.Time
+
+	mu     sync.Mutex
+	labels map[api.WorkerID]build.Labels
+}
+
+// NewWorkerLabels создаёт WorkerLabels, которое в течение grace после создания не отвергает джобы.
+//
+// Сразу после запуска координатора воркеры ещё не успели прислать heartbeat, поэтому координатор
+// передаёт в grace Config.LabelsGrace, по умолчанию равный WorkerTimeout. Когда grace истекает,
+// координатор заново проверяет незавершённые сборки через Check.
+func NewWorkerLabels(grace time.Duration, now func() time.Time) *WorkerLabels {
+	return &WorkerLabels{
+		now:      now,
+		deadline: now().Add(grace),
+		labels:   make(map[api.WorkerID]build.Labels),
+	}
+}
+
+// Set запоминает метки, которые воркер прислал в HeartbeatRequest.Labels.
+func (w *WorkerLabels) Set(workerID api.WorkerID, labels build.Labels) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.labels[workerID] = labels
+}
+
+// Remove забывает воркера, которого Liveness.Expire объявил мёртвым.
+func (w *WorkerLabels) Remove(workerID api.WorkerID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.labels, workerID)
+}
+
+// Check проверяет, что для каждого джоба есть хотя бы один живой воркер, метки которого удовлетворяют
+// build.Job.Requires.
+//
+// Джобы без Requires подходят любому воркеру и не проверяются. Возвращаемая ошибка объединяет
+// ошибки всех неудовлетворимых джобов, каждая из них оборачивает ErrNoMatchingWorker.
+//
+// Пока не прошёл grace, переданный в NewWorkerLabels, Check всегда возвращает nil: подходящий воркер
+// может ещё не успеть зарегистрироваться.
+func (w *WorkerLabels) Check(jobs []build.Job) error {
+	if w.now().Before(w.deadline) {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for i := range jobs {
+		job := &jobs[i]
+		if len(job.Requires) == 0 || w.satisfied(job.Requires) {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("job %q requires %s: %w", job.Name, job.Requires, ErrNoMatchingWorker))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *WorkerLabels) satisfied(required build.Labels) bool {
+	for _, labels := range w.labels {
+		if labels.Satisfies(required) {
+			return true
+		}
+	}
+	return false
+}
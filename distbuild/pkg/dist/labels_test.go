@@ -0,0 +1,46 @@
+package dist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+)
+
+func TestWorkerLabels(t *testing.T) {
+	now := time.Now()
+	w := dist.NewWorkerLabels(time.Minute, func() time.Time { return now })
+
+	jobs := []build.Job{
+		{ID: build.ID{'a'}, Name: "generate"},
+		{ID: build.ID{'b'}, Name: "compile arm64", Requires: build.Labels{build.LabelArch: "arm64"}},
+		{ID: build.ID{'c'}, Name: "compile amd64", Requires: build.Labels{build.LabelArch: "amd64"}},
+	}
+
+	// Сразу после старта воркеры ещё не успели зарегистрироваться.
+	require.NoError(t, w.Check(jobs))
+
+	now = now.Add(time.Minute)
+	err := w.Check(jobs)
+	require.ErrorIs(t, err, dist.ErrNoMatchingWorker)
+	require.Contains(t, err.Error(), `"compile arm64" requires arch=arm64`)
+	require.Contains(t, err.Error(), `"compile amd64" requires arch=amd64`)
+	require.NotContains(t, err.Error(), "generate")
+
+	w.Set("w0", build.Labels{build.LabelOS: "linux", build.LabelArch: "arm64"})
+	w.Set("w1", build.Labels{build.LabelOS: "linux", build.LabelArch: "amd64"})
+	require.NoError(t, w.Check(jobs))
+
+	w.Remove("w1")
+	err = w.Check(jobs)
+	require.ErrorIs(t, err, dist.ErrNoMatchingWorker)
+	require.NotContains(t, err.Error(), "arm64")
+
+	w.Set("w0", build.Labels{build.LabelOS: "linux", build.LabelArch: "amd64"})
+	err = w.Check(jobs)
+	require.Contains(t, err.Error(), "arm64")
+	require.NotContains(t, err.Error(), "amd64")
+}
@@ -0,0 +1,85 @@
+package dist
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// DefaultWorkerTimeout задаёт, через сколько времени без heartbeat-ов воркер считается мёртвым.
+const DefaultWorkerTimeout = 30 * time.Second
+
+// DeadWorkerTTL задаёт, сколько Liveness помнит мёртвого воркера.
+//
+// Воркер, вернувшийся позже, считается новым, и Heartbeat для него возвращает false.
+const DeadWorkerTTL = 24 * time.Hour
+
+// Liveness следит за тем, какие воркеры присылают heartbeat-ы.
+type Liveness struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	lastSeen map[api.WorkerID]time.Time
+	// dead хранит время, когда воркер был объявлен мёртвым.
+	dead map[api.WorkerID]time.Time
+}
+
+func NewLiveness(timeout time.Duration, now func() time.Time) *Liveness {
+	return &Liveness{
+		timeout:  timeout,
+		now:      now,
+		lastSeen: make(map[api.WorkerID]time.Time),
+		dead:     make(map[api.WorkerID]time.Time),
+	}
+}
+
+// Heartbeat запоминает, что воркер только что прислал heartbeat.
+//
+// Heartbeat возвращает true, если воркер раньше был объявлен мёртвым и теперь вернулся.
+// Такой воркер нужно зарегистрировать заново, как будто координатор видит его впервые.
+func (l *Liveness) Heartbeat(workerID api.WorkerID) (rejoined bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastSeen[workerID] = l.now()
+
+	if _, ok := l.dead[workerID]; ok {
+		delete(l.dead, workerID)
+		return true
+	}
+
+	return false
+}
+
+// Expire объявляет мёртвыми воркеров, от которых не было heartbeat-а дольше таймаута, и возвращает их.
+//
+// Каждый воркер возвращается из Expire только один раз, пока он снова не пришлёт heartbeat.
+// Заодно Expire забывает воркеров, которые мертвы дольше DeadWorkerTTL.
+func (l *Liveness) Expire() []api.WorkerID {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	var expired []api.WorkerID
+	deadline := now.Add(-l.timeout)
+
+	for workerID, lastSeen := range l.lastSeen {
+		if lastSeen.Before(deadline) {
+			expired = append(expired, workerID)
+
+			delete(l.lastSeen, workerID)
+			l.dead[workerID] = now
+		}
+	}
+
+	for workerID, diedAt := range l.dead {
+		if now.Sub(diedAt) > DeadWorkerTTL {
+			delete(l.dead, workerID)
+		}
+	}
+
+	return expired
+}
@@ -0,0 +1,43 @@
+package dist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+)
+
+func TestLiveness(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := dist.NewLiveness(time.Minute, func() time.Time { return now })
+
+	require.False(t, l.Heartbeat("w0"))
+	require.False(t, l.Heartbeat("w1"))
+
+	now = now.Add(40 * time.Second)
+	require.False(t, l.Heartbeat("w1"))
+	require.Empty(t, l.Expire())
+
+	now = now.Add(40 * time.Second)
+	require.Equal(t, []api.WorkerID{"w0"}, l.Expire())
+	require.Empty(t, l.Expire())
+
+	require.True(t, l.Heartbeat("w0"))
+	require.False(t, l.Heartbeat("w0"))
+
+	now = now.Add(2 * time.Minute)
+	require.ElementsMatch(t, []api.WorkerID{"w0", "w1"}, l.Expire())
+
+	now = now.Add(dist.DeadWorkerTTL / 2)
+	require.Empty(t, l.Expire())
+	require.True(t, l.Heartbeat("w0"))
+
+	// Воркер, который не возвращался дольше DeadWorkerTTL, забыт и регистрируется как новый.
+	now = now.Add(dist.DeadWorkerTTL)
+	require.Equal(t, []api.WorkerID{"w0"}, l.Expire())
+	require.Empty(t, l.Expire())
+	require.False(t, l.Heartbeat("w1"))
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// traceEvent - одно событие в формате Chrome trace event.
+//
+// Формат описан в https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Phase string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   int64          `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+type trace struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// coordinatorTID задаёт поток, на котором рисуются события координатора. Каждый воркер получает свой поток.
+const coordinatorTID = 0
+
+// WriteChromeTrace записывает события в формате Chrome trace event, который открывают chrome://tracing и Perfetto.
+//
+// Выполнение джоба и скачивание артефакта рисуются отрезками на потоке воркера, остальные события - отметками.
+func (l *Log) WriteChromeTrace(w io.Writer) error {
+	events := l.Events()
+
+	var start time.Time
+	if len(events) != 0 {
+		start = events[0].Time
+	}
+
+	micros := func(t time.Time) int64 {
+		return t.Sub(start).Microseconds()
+	}
+
+	out := trace{
+		TraceEvents: []traceEvent{{
+			Name: "thread_name", Phase: "M", TID: coordinatorTID,
+			Args: map[string]any{"name": "coordinator"},
+		}},
+		DisplayTimeUnit: "ms",
+	}
+
+	tids := map[api.WorkerID]int{}
+	tid := func(workerID api.WorkerID) int {
+		if workerID == "" {
+			return coordinatorTID
+		}
+
+		if id, ok := tids[workerID]; ok {
+			return id
+		}
+
+		id := len(tids) + 1
+		tids[workerID] = id
+		out.TraceEvents = append(out.TraceEvents, traceEvent{
+			Name: "thread_name", Phase: "M", TID: id,
+			Args: map[string]any{"name": string(workerID)},
+		})
+		return id
+	}
+
+	for _, e := range events {
+		te := traceEvent{
+			Name:  e.Name,
+			Cat:   string(e.Kind),
+			Phase: "i",
+			Scope: "t",
+			TS:    micros(e.Time),
+			TID:   tid(e.Worker),
+			Args:  map[string]any{"job_id": e.JobID.String()},
+		}
+
+		switch e.Kind {
+		case JobStarted:
+			// Отрезок выполнения рисуется по событию JobFinished.
+			continue
+
+		case JobPicked:
+			te.Args["queue"] = e.Queue
+
+		case ArtifactDownloaded:
+			te.Phase, te.Scope = "X", ""
+			te.Name = "download " + e.Download.ID.String()
+			te.Dur = e.Duration.Microseconds()
+			te.Args["from"] = string(e.Download.From)
+			te.Args["size"] = e.Download.Size
+
+		case JobFinished:
+			if e.Duration != 0 {
+				te.Phase, te.Scope = "X", ""
+				te.TS = micros(e.Time.Add(-e.Duration))
+				te.Dur = e.Duration.Microseconds()
+			}
+
+			te.Args["exit_code"] = e.ExitCode
+			if e.Reason != "" {
+				te.Args["reason"] = string(e.Reason)
+			}
+			if e.Error != "" {
+				te.Args["error"] = e.Error
+			}
+		}
+
+		out.TraceEvents = append(out.TraceEvents, te)
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
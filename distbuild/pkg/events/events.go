@@ -0,0 +1,236 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Kind задаёт тип события.
+type Kind string
+
+const (
+	// JobScheduled - координатор поставил джоб в очередь шедулера.
+	JobScheduled Kind = "scheduled"
+
+	// JobPicked - воркер Worker забрал джоб из очереди Queue.
+	JobPicked Kind = "picked"
+
+	// ArtifactDownloaded - воркер Worker скачал артефакт зависимости перед запуском джоба.
+	ArtifactDownloaded Kind = "artifact_downloaded"
+
+	// JobStarted - воркер Worker запустил первую команду джоба.
+	JobStarted Kind = "started"
+
+	// JobFinished - джоб завершился на воркере Worker.
+	JobFinished Kind = "finished"
+
+	// JobCached - результат джоба нашёлся в кеше, и джоб не запускался. Worker задаёт воркер, в кеше
+	// которого нашёлся артефакт. Пустой Worker означает, что результат уже был в журнале координатора.
+	JobCached Kind = "cached"
+)
+
+// Event описывает одно событие сборки.
+//
+// Поля, не относящиеся к Kind, остаются нулевыми.
+type Event struct {
+	Time time.Time
+	Kind Kind
+
+	JobID build.ID
+	Name  string
+
+	Worker api.WorkerID
+
+	// Queue задаёт очередь шедулера, из которой забрали джоб, одну из metrics.QueueGlobal,
+	// metrics.QueueLocal1 и metrics.QueueLocal2.
+	Queue string
+
+	// Download описывает скачанный артефакт для события ArtifactDownloaded.
+	Download *api.ArtifactDownload
+
+	// Duration задаёт длительность события: время скачивания для ArtifactDownloaded
+	// и время выполнения для JobFinished.
+	Duration time.Duration
+
+	ExitCode int
+	Reason   api.FailureReason
+	Error    string
+}
+
+// FromJobResult превращает отчёт воркера о джобе name в события ArtifactDownloaded, JobStarted и JobFinished.
+//
+// Для res.Cached FromJobResult возвращает одно событие JobCached.
+func FromJobResult(workerID api.WorkerID, name string, res *api.JobResult) []Event {
+	if res.Cached {
+		return []Event{{
+			Time:   res.FinishedAt,
+			Kind:   JobCached,
+			JobID:  res.ID,
+			Name:   name,
+			Worker: workerID,
+		}}
+	}
+
+	var events []Event
+
+	for i := range res.Downloads {
+		download := res.Downloads[i]
+		events = append(events, Event{
+			Time:     download.StartedAt,
+			Kind:     ArtifactDownloaded,
+			JobID:    res.ID,
+			Name:     name,
+			Worker:   workerID,
+			Download: &download,
+			Duration: download.Duration,
+		})
+	}
+
+	if !res.StartedAt.IsZero() {
+		events = append(events, Event{
+			Time:   res.StartedAt,
+			Kind:   JobStarted,
+			JobID:  res.ID,
+			Name:   name,
+			Worker: workerID,
+		})
+	}
+
+	finished := Event{
+		Time:     res.FinishedAt,
+		Kind:     JobFinished,
+		JobID:    res.ID,
+		Name:     name,
+		Worker:   workerID,
+		ExitCode: res.ExitCode,
+		Reason:   res.Reason,
+	}
+
+	if !res.StartedAt.IsZero() && !res.FinishedAt.IsZero() {
+		finished.Duration = res.FinishedAt.Sub(res.StartedAt)
+	}
+	if res.Error != nil {
+		finished.Error = *res.Error
+	}
+
+	return append(events, finished)
+}
+
+// Log хранит события одной сборки.
+type Log struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+// Add добавляет события в лог. Если у события не задано Time, оно получает текущее время.
+func (l *Log) Add(events ...Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range events {
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		l.events = append(l.events, e)
+	}
+}
+
+// Events возвращает события, отсортированные по времени.
+//
+// Время событий воркеров измерено по часам воркера, поэтому при расхождении часов порядок может
+// не совпадать с реальным.
+func (l *Log) Events() []Event {
+	l.mu.Lock()
+	events := slices.Clone(l.events)
+	l.mu.Unlock()
+
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return a.Time.Compare(b.Time)
+	})
+	return events
+}
+
+// WriteJSONLines записывает события в w, по одному json объекту на строку.
+func (l *Log) WriteJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, e := range l.Events() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultMaxBuilds задаёт, сколько последних сборок хранит Store по умолчанию.
+const DefaultMaxBuilds = 100
+
+// Store хранит логи последних сборок.
+type Store struct {
+	maxBuilds int
+
+	mu     sync.Mutex
+	logs   map[build.ID]*Log
+	builds []build.ID
+}
+
+// NewStore создаёт хранилище, которое помнит не больше maxBuilds сборок. Нулевой maxBuilds означает DefaultMaxBuilds.
+func NewStore(maxBuilds int) *Store {
+	if maxBuilds == 0 {
+		maxBuilds = DefaultMaxBuilds
+	}
+
+	return &Store{
+		maxBuilds: maxBuilds,
+		logs:      make(map[build.ID]*Log),
+	}
+}
+
+// Log возвращает лог сборки buildID, создавая его при первом обращении.
+//
+// Создание лога новой сборки вытесняет лог самой старой сборки, если их стало больше maxBuilds.
+func (s *Store) Log(buildID build.ID) *Log {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.logs[buildID]; ok {
+		return l
+	}
+
+	l := NewLog()
+	s.logs[buildID] = l
+	s.builds = append(s.builds, buildID)
+
+	if len(s.builds) > s.maxBuilds {
+		delete(s.logs, s.builds[0])
+		s.builds = s.builds[1:]
+	}
+
+	return l
+}
+
+// Get возвращает лог сборки buildID, если он есть.
+func (s *Store) Get(buildID build.ID) (*Log, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logs[buildID]
+	return l, ok
+}
+
+// Builds возвращает сборки, логи которых есть в хранилище, от старых к новым.
+func (s *Store) Builds() []build.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.builds)
+}
@@ -0,0 +1,202 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/events"
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestLog() *events.Log {
+	failed := "exit status 1"
+
+	l := events.NewLog()
+	l.Add(
+		events.Event{Time: t0, Kind: events.JobScheduled, JobID: build.ID{'a'}, Name: "compile"},
+		events.Event{Time: t0.Add(time.Millisecond), Kind: events.JobPicked, JobID: build.ID{'a'}, Name: "compile", Worker: "w0", Queue: metrics.QueueGlobal},
+	)
+	l.Add(events.FromJobResult("w0", "compile", &api.JobResult{
+		ID:       build.ID{'a'},
+		ExitCode: 1,
+		Error:    &failed,
+		Downloads: []api.ArtifactDownload{
+			{ID: build.ID{'b'}, From: "w1", Size: 42, StartedAt: t0.Add(2 * time.Millisecond), Duration: time.Millisecond},
+		},
+		StartedAt:  t0.Add(5 * time.Millisecond),
+		FinishedAt: t0.Add(15 * time.Millisecond),
+	})...)
+	return l
+}
+
+func TestFromJobResult(t *testing.T) {
+	res := &api.JobResult{ID: build.ID{'a'}}
+
+	evs := events.FromJobResult("w0", "compile", res)
+	require.Len(t, evs, 1)
+	require.Equal(t, events.JobFinished, evs[0].Kind)
+	require.Zero(t, evs[0].Duration)
+
+	res.Cached = true
+	evs = events.FromJobResult("w0", "compile", res)
+	require.Len(t, evs, 1)
+	require.Equal(t, events.JobCached, evs[0].Kind)
+	require.Equal(t, api.WorkerID("w0"), evs[0].Worker)
+
+	evs = newTestLog().Events()
+	require.Len(t, evs, 5)
+
+	var kinds []events.Kind
+	for _, e := range evs {
+		kinds = append(kinds, e.Kind)
+	}
+	require.Equal(t, []events.Kind{
+		events.JobScheduled,
+		events.JobPicked,
+		events.ArtifactDownloaded,
+		events.JobStarted,
+		events.JobFinished,
+	}, kinds)
+
+	require.Equal(t, 10*time.Millisecond, evs[4].Duration)
+	require.Equal(t, "exit status 1", evs[4].Error)
+	require.Equal(t, api.WorkerID("w1"), evs[2].Download.From)
+}
+
+func TestLogOrder(t *testing.T) {
+	l := events.NewLog()
+	l.Add(events.Event{Time: t0.Add(time.Second), Kind: events.JobFinished})
+	l.Add(events.Event{Time: t0, Kind: events.JobStarted})
+	l.Add(events.Event{Kind: events.JobCached})
+
+	evs := l.Events()
+	require.Equal(t, events.JobStarted, evs[0].Kind)
+	require.Equal(t, events.JobFinished, evs[1].Kind)
+	require.Equal(t, events.JobCached, evs[2].Kind)
+	require.False(t, evs[2].Time.IsZero())
+}
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestLog().WriteJSONLines(&buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+
+	var e events.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &e))
+	require.Equal(t, events.JobPicked, e.Kind)
+	require.Equal(t, build.ID{'a'}, e.JobID)
+	require.Equal(t, metrics.QueueGlobal, e.Queue)
+}
+
+type chromeEvent struct {
+	Name  string         `json:"name"`
+	Phase string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   int64          `json:"dur"`
+	TID   int            `json:"tid"`
+	Args  map[string]any `json:"args"`
+}
+
+func TestChromeTrace(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestLog().WriteChromeTrace(&buf))
+
+	var trace struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+
+	threads := map[int]string{}
+	spans := map[string]chromeEvent{}
+	var instants []string
+
+	for _, e := range trace.TraceEvents {
+		switch e.Phase {
+		case "M":
+			threads[e.TID] = e.Args["name"].(string)
+		case "X":
+			spans[e.Name] = e
+		case "i":
+			instants = append(instants, e.Name)
+		}
+	}
+
+	require.Equal(t, map[int]string{0: "coordinator", 1: "w0"}, threads)
+	require.Equal(t, []string{"compile", "compile"}, instants)
+
+	job := spans["compile"]
+	require.Equal(t, int64(5000), job.TS)
+	require.Equal(t, int64(10000), job.Dur)
+	require.Equal(t, 1, job.TID)
+	require.Equal(t, "exit status 1", job.Args["error"])
+
+	download := spans["download "+build.ID{'b'}.String()]
+	require.Equal(t, int64(2000), download.TS)
+	require.Equal(t, int64(1000), download.Dur)
+	require.Equal(t, "w1", download.Args["from"])
+}
+
+func TestStore(t *testing.T) {
+	s := events.NewStore(2)
+
+	a := s.Log(build.ID{'a'})
+	require.Same(t, a, s.Log(build.ID{'a'}))
+
+	s.Log(build.ID{'b'})
+	s.Log(build.ID{'c'})
+
+	_, ok := s.Get(build.ID{'a'})
+	require.False(t, ok)
+
+	_, ok = s.Get(build.ID{'c'})
+	require.True(t, ok)
+
+	require.Equal(t, []build.ID{{'b'}, {'c'}}, s.Builds())
+}
+
+func TestHandler(t *testing.T) {
+	s := events.NewStore(0)
+	s.Log(build.ID{'a'}).Add(newTestLog().Events()...)
+
+	mux := http.NewServeMux()
+	events.NewHandler(zaptest.NewLogger(t), s).Register(mux)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events"+query, nil))
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var builds []build.ID
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &builds))
+	require.Equal(t, []build.ID{{'a'}}, builds)
+
+	w = get("?build_id=" + build.ID{'a'}.String())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 5)
+
+	w = get("?format=chrome&build_id=" + build.ID{'a'}.String())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, json.Valid(w.Body.Bytes()))
+
+	require.Equal(t, http.StatusNotFound, get("?build_id="+build.ID{'b'}.String()).Code)
+	require.Equal(t, http.StatusBadRequest, get("?build_id=xyz").Code)
+	require.Equal(t, http.StatusBadRequest, get("?format=xml&build_id="+build.ID{'a'}.String()).Code)
+}
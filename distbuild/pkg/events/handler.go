@@ -0,0 +1,76 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+const (
+	// FormatJSONLines - формат по умолчанию, один Event на строку.
+	FormatJSONLines = "jsonl"
+
+	// FormatChrome - формат Chrome trace event, см. Log.WriteChromeTrace.
+	FormatChrome = "chrome"
+)
+
+// Handler отдаёт логи событий из Store.
+//
+// GET /events возвращает json список сборок, логи которых есть в Store.
+// GET /events?build_id=1234&format=chrome возвращает лог одной сборки в формате format.
+type Handler struct {
+	l     *zap.Logger
+	store *Store
+}
+
+func NewHandler(l *zap.Logger, store *Store) *Handler {
+	return &Handler{l: l, store: store}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /events", h)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if !query.Has("build_id") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(h.store.Builds()); err != nil {
+			h.l.Warn("error writing build list", zap.Error(err))
+		}
+		return
+	}
+
+	var buildID build.ID
+	if err := buildID.UnmarshalText([]byte(query.Get("build_id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log, ok := h.store.Get(buildID)
+	if !ok {
+		http.Error(w, "build not found", http.StatusNotFound)
+		return
+	}
+
+	var err error
+	switch format := query.Get("format"); format {
+	case "", FormatJSONLines:
+		w.Header().Set("Content-Type", "application/jsonl")
+		err = log.WriteJSONLines(w)
+	case FormatChrome:
+		w.Header().Set("Content-Type", "application/json")
+		err = log.WriteChromeTrace(w)
+	default:
+		http.Error(w, "unknown format "+format, http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		h.l.Warn("error writing events", zap.Stringer("build_id", buildID), zap.Error(err))
+	}
+}
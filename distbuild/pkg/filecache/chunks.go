@@ -0,0 +1,84 @@
+package filecache
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/compression"
+)
+
+// Missing возвращает те ID из ids, которых нет в кеше.
+func (c *Cache) Missing(ids []build.ID) []build.ID {
+	var missing []build.ID
+	for _, id := range ids {
+		_, unlock, err := c.Get(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		unlock()
+	}
+	return missing
+}
+
+// Assemble собирает файл из кусков, которые уже лежат в кеше.
+//
+// Куски склеиваются в порядке chunks. Если sha1 результата не совпал с file, файл не попадает в кеш
+// и Assemble возвращает ошибку, обёрнутую вокруг compression.ErrChecksumMismatch.
+// Если файл уже есть в кеше, Assemble ничего не делает.
+func (c *Cache) Assemble(file build.ID, chunks []build.ID) error {
+	w, abort, err := c.Write(file)
+	if errors.Is(err, ErrExists) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	h := sha1.New()
+	if err = c.copyChunks(io.MultiWriter(w, h), chunks); err != nil {
+		_ = abort()
+		return err
+	}
+
+	var sum build.ID
+	copy(sum[:], h.Sum(nil))
+
+	if sum != file {
+		_ = abort()
+		return fmt.Errorf("%w: expected %s, got %s", compression.ErrChecksumMismatch, file, sum)
+	}
+
+	return w.Close()
+}
+
+func (c *Cache) copyChunks(w io.Writer, chunks []build.ID) error {
+	for _, id := range chunks {
+		path, unlock, err := c.Get(id)
+		if err != nil {
+			return fmt.Errorf("chunk %s: %w", id, err)
+		}
+
+		err = func() error {
+			defer unlock()
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+
+			_, err = io.Copy(w, f)
+			return err
+		}()
+
+		if err != nil {
+			return fmt.Errorf("chunk %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package filecache_test
+
+import (
+	"crypto/sha1"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/compression"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+func writeFile(t *testing.T, cache *testCache, content string) build.ID {
+	id := build.ID(sha1.Sum([]byte(content)))
+
+	w, _, err := cache.Write(id)
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return id
+}
+
+func TestAssemble(t *testing.T) {
+	cache := newCache(t)
+
+	foo := writeFile(t, cache, "foo ")
+	bar := writeFile(t, cache, "bar")
+	baz := build.ID(sha1.Sum([]byte("baz")))
+
+	require.Equal(t, []build.ID{baz}, cache.Missing([]build.ID{foo, baz, bar}))
+
+	file := build.ID(sha1.Sum([]byte("foo bar")))
+	require.ErrorIs(t, cache.Assemble(file, []build.ID{bar, foo}), compression.ErrChecksumMismatch)
+	require.ErrorIs(t, cache.Assemble(file, []build.ID{foo, baz}), filecache.ErrNotFound)
+
+	require.NoError(t, cache.Assemble(file, []build.ID{foo, bar}))
+	require.NoError(t, cache.Assemble(file, []build.ID{foo, bar}))
+
+	path, unlock, err := cache.Get(file)
+	require.NoError(t, err)
+	defer unlock()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "foo bar", string(content))
+}
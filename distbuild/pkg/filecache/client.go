@@ -4,6 +4,7 @@ package filecache
 
 import (
 	"context"
+	"net/http"
 
 	"go.uber.org/zap"
 
@@ -17,10 +18,40 @@ func NewClient(l *zap.Logger, endpoint string) *Client {
 	panic("implement me")
 }
 
+// NewVerifyingClient создаёт клиента для файлов, build.ID которых равен sha1 их содержимого.
+//
+// Такой клиент просит сервер проверить содержимое при заливке и сам проверяет содержимое
+// скачанных файлов. Если хеш не совпал, файл не попадает в кеш.
+func NewVerifyingClient(l *zap.Logger, endpoint string) *Client {
+	panic("implement me")
+}
+
+// ClientConfig задаёт необязательные настройки клиента.
+type ClientConfig struct {
+	// Verify включает проверку содержимого, как у клиента из NewVerifyingClient.
+	Verify bool
+
+	// HTTPClient задаёт клиента, через которого идут запросы. Нулевое значение означает http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func NewClientWithConfig(l *zap.Logger, endpoint string, config ClientConfig) *Client {
+	panic("implement me")
+}
+
 func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) error {
 	panic("implement me")
 }
 
+// UploadChunked заливает файл по кускам, которые уже есть на сервере, пропуская их.
+//
+// Файл режется на куски через chunker.Split. Клиент спрашивает у сервера, каких кусков не хватает,
+// заливает только их, а затем просит сервер собрать из кусков файл. id должен быть равен sha1 содержимого файла.
+// Если заливка оборвалась, повторный вызов UploadChunked зальёт только те куски, которые не успели дойти до сервера.
+func (c *Client) UploadChunked(ctx context.Context, id build.ID, localPath string) error {
+	panic("implement me")
+}
+
 func (c *Client) Download(ctx context.Context, localCache *Cache, id build.ID) error {
 	panic("implement me")
 }
@@ -3,6 +3,9 @@ package filecache_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"errors"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -127,3 +130,111 @@ func TestFileDownload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foobar"), content)
 }
+
+func TestFileVerifiedUpload(t *testing.T) {
+	env := newEnv(t)
+	client := filecache.NewVerifyingClient(zaptest.NewLogger(t), env.server.URL)
+
+	content := []byte("foobar")
+	tmpFilePath := filepath.Join(env.cache.tmpDir, "foo.txt")
+	require.NoError(t, os.WriteFile(tmpFilePath, content, 0666))
+
+	ctx := context.Background()
+
+	id := build.ID(sha1.Sum(content))
+	require.NoError(t, client.Upload(ctx, id, tmpFilePath))
+
+	localCache := newCache(t)
+	require.NoError(t, client.Download(ctx, localCache.Cache, id))
+
+	wrongID := build.ID{0x01}
+	require.Error(t, client.Upload(ctx, wrongID, tmpFilePath))
+
+	_, _, err := env.cache.Get(wrongID)
+	require.Truef(t, errors.Is(err, filecache.ErrNotFound), "%v", err)
+
+	// Файл залит клиентом без проверки, но его содержимое не совпадает с id.
+	require.NoError(t, env.client.Upload(ctx, wrongID, tmpFilePath))
+	require.Error(t, client.Download(ctx, localCache.Cache, wrongID))
+}
+
+func TestFileChunkedUpload(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	cache := newCache(t)
+
+	mux := http.NewServeMux()
+	filecache.NewHandler(l, cache.Cache).Register(mux)
+
+	var (
+		mu        sync.Mutex
+		puts      int
+		failAfter = -1
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			mu.Lock()
+			puts++
+			broken := failAfter >= 0 && puts > failAfter
+			mu.Unlock()
+
+			if broken {
+				http.Error(w, "connection reset", http.StatusBadGateway)
+				return
+			}
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := filecache.NewVerifyingClient(l, server.URL)
+	ctx := context.Background()
+
+	content := make([]byte, 4<<20)
+	_, _ = rand.New(rand.NewSource(0)).Read(content)
+
+	upload := func(content []byte) (build.ID, int, error) {
+		path := filepath.Join(t.TempDir(), "generated.go")
+		require.NoError(t, os.WriteFile(path, content, 0666))
+
+		mu.Lock()
+		puts = 0
+		mu.Unlock()
+
+		id := build.ID(sha1.Sum(content))
+		err := client.UploadChunked(ctx, id, path)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return id, puts, err
+	}
+
+	// Соединение рвётся посреди заливки. Повторная заливка докачивает только недостающие куски.
+	failAfter = 10
+	_, _, err := upload(content)
+	require.Error(t, err)
+
+	failAfter = -1
+	id, resumed, err := upload(content)
+	require.NoError(t, err)
+
+	_, full, err := upload(content)
+	require.NoError(t, err)
+	require.Zero(t, full)
+
+	path, unlock, err := cache.Get(id)
+	require.NoError(t, err)
+	actual, err := os.ReadFile(path)
+	unlock()
+	require.NoError(t, err)
+	require.Equal(t, content, actual)
+
+	edited := bytes.Clone(content)
+	copy(edited[len(edited)/2:], "// edited\n")
+
+	_, changed, err := upload(edited)
+	require.NoError(t, err)
+	require.LessOrEqual(t, changed, 2)
+	require.Less(t, changed, resumed)
+}
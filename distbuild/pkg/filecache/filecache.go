@@ -39,7 +39,12 @@ type Cache struct {
 }
 
 func New(rootDir string) (*Cache, error) {
-	cache, err := artifact.NewCache(rootDir)
+	return NewWithPolicy(rootDir, artifact.EvictionPolicy{})
+}
+
+// NewWithPolicy создаёт кеш файлов, размер которого ограничен policy.
+func NewWithPolicy(rootDir string, policy artifact.EvictionPolicy) (*Cache, error) {
+	cache, err := artifact.NewCacheWithPolicy(rootDir, policy)
 	if err != nil {
 		return nil, err
 	}
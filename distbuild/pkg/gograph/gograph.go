@@ -0,0 +1,424 @@
+package gograph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrUnsupported = errors.New("package is not supported")
+
+// Config описывает, из каких пакетов и каких джобов строить граф.
+type Config struct {
+	// Dir задаёт корень модуля, то есть директорию с go.mod.
+	//
+	// Dir становится директорией с исходным кодом сборки. Все пакеты, кроме стандартной библиотеки,
+	// должны лежать внутри Dir. Зависимости из других модулей нужно завендорить через go mod vendor.
+	Dir string
+
+	// Patterns передаются в go list. По умолчанию используется "./...".
+	Patterns []string
+
+	// Vet добавляет в граф джоб go vet для каждого пакета модуля.
+	Vet bool
+
+	// Test добавляет в граф джоб go test для каждого пакета модуля, в котором есть тесты.
+	Test bool
+}
+
+// Package содержит поля из вывода go list -json, нужные для построения графа.
+type Package struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	Standard   bool
+	DepOnly    bool
+	ForTest    string
+
+	GoFiles      []string
+	CgoFiles     []string
+	SFiles       []string
+	EmbedFiles   []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+
+	Imports      []string
+	ImportMap    map[string]string
+	TestImports  []string
+	XTestImports []string
+
+	Module *struct {
+		GoVersion string
+	}
+
+	Error *struct {
+		Err string
+	}
+}
+
+// List запускает go list -json -deps в директории config.Dir.
+func List(ctx context.Context, config Config) ([]*Package, error) {
+	patterns := config.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	args := []string{"list", "-json", "-deps"}
+	if config.Test {
+		args = append(args, "-test")
+	}
+
+	cmd := exec.CommandContext(ctx, "go", append(args, patterns...)...)
+	cmd.Dir = config.Dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list: %w\n%s", err, stderr.String())
+	}
+
+	var pkgs []*Package
+	dec := json.NewDecoder(&stdout)
+	for {
+		var p Package
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+
+		if p.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", p.ImportPath, p.Error.Err)
+		}
+
+		// Тестовые варианты пакетов собирает сам go test внутри тестового джоба.
+		if p.ForTest != "" || strings.HasSuffix(p.ImportPath, ".test") {
+			continue
+		}
+
+		pkgs = append(pkgs, &p)
+	}
+
+	return pkgs, nil
+}
+
+// Generate строит граф сборки модуля из config.Dir.
+//
+// ID джобов графа вычисляются через build.ComputeJobIDs.
+func Generate(ctx context.Context, config Config) (*build.Graph, error) {
+	pkgs, err := List(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	goVersion, err := exec.CommandContext(ctx, "go", "env", "GOVERSION").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env: %w", err)
+	}
+
+	graph, err := NewGraph(pkgs, strings.TrimSpace(string(goVersion)), config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := build.ComputeJobIDs(graph, config.Dir); err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+type node struct {
+	pkg   *Package
+	dir   string
+	build *build.Job
+}
+
+// NewGraph строит граф сборки по списку пакетов из List.
+//
+// goVersion записывается в importcfg каждого джоба, чтобы разные версии тулчейна давали разные ID джобов.
+// ID джобов в возвращённом графе случайные.
+func NewGraph(pkgs []*Package, goVersion string, config Config) (*build.Graph, error) {
+	root, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]*node{}
+	for _, p := range pkgs {
+		if p.Standard {
+			continue
+		}
+
+		if len(p.CgoFiles) != 0 || len(p.SFiles) != 0 || len(p.EmbedFiles) != 0 {
+			return nil, fmt.Errorf("%w: %s uses cgo, assembly or embed", ErrUnsupported, p.ImportPath)
+		}
+
+		dir, err := filepath.Rel(root, p.Dir)
+		if err != nil || !filepath.IsLocal(dir) {
+			return nil, fmt.Errorf("%w: %s is outside of %s, vendor it first", ErrUnsupported, p.ImportPath, root)
+		}
+
+		nodes[p.ImportPath] = &node{pkg: p, dir: filepath.ToSlash(dir)}
+	}
+
+	var graph build.Graph
+
+	// go list -deps выводит зависимости раньше пакетов, которые от них зависят.
+	for _, p := range pkgs {
+		n, ok := nodes[p.ImportPath]
+		if !ok {
+			continue
+		}
+
+		n.build = buildJob(n, nodes, goVersion)
+		graph.Jobs = append(graph.Jobs, *n.build)
+	}
+
+	modFiles := moduleFiles(root)
+
+	for _, p := range pkgs {
+		n, ok := nodes[p.ImportPath]
+		if !ok || p.DepOnly {
+			continue
+		}
+
+		if p.Name == "main" {
+			graph.Jobs = append(graph.Jobs, linkJob(n, nodes, goVersion))
+		}
+
+		if config.Vet {
+			graph.Jobs = append(graph.Jobs, goJob("vet", n, modFiles, closure(nodes, p.Imports)))
+		}
+
+		if config.Test && len(p.TestGoFiles)+len(p.XTestGoFiles) != 0 {
+			imports := slices.Concat(p.Imports, p.TestImports, p.XTestImports)
+			job := goJob("test", n, modFiles, closure(nodes, imports))
+
+			for _, name := range slices.Concat(p.TestGoFiles, p.XTestGoFiles) {
+				job.Inputs = append(job.Inputs, path.Join(n.dir, name))
+			}
+			job.Inputs = append(job.Inputs, testdata(root, n.dir)...)
+
+			graph.Jobs = append(graph.Jobs, job)
+		}
+	}
+
+	return &graph, nil
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`).Replace(s)
+}
+
+func depPath(id build.ID) string {
+	return fmt.Sprintf("{{index .Deps %q}}", id)
+}
+
+// importcfg возвращает команды, которые пишут importcfg для компилятора или линкера в файл name.
+//
+// Пакеты стандартной библиотеки не собираются в графе. Их export data берётся из тулчейна воркера.
+func importcfg(name, goVersion string, deps []*node, importMap map[string]string, std []string, stdDeps bool) []build.Cmd {
+	var cfg strings.Builder
+	fmt.Fprintf(&cfg, "# %s\n", escape(goVersion))
+
+	for _, from := range slices.Sorted(maps.Keys(importMap)) {
+		fmt.Fprintf(&cfg, "importmap %s=%s\n", escape(from), escape(importMap[from]))
+	}
+
+	for _, dep := range deps {
+		fmt.Fprintf(&cfg, "packagefile %s=%s/lib.a\n", escape(dep.pkg.ImportPath), depPath(dep.build.ID))
+	}
+
+	cmds := []build.Cmd{{CatTemplate: cfg.String(), CatOutput: "{{.OutputDir}}/" + name}}
+	if len(std) == 0 {
+		return cmds
+	}
+
+	list := "go list -export"
+	if stdDeps {
+		list += " -deps"
+	}
+
+	script := fmt.Sprintf("%s -f '{{if .Export}}packagefile {{.ImportPath}}={{.Export}}{{end}}' %s",
+		list, strings.Join(std, " "))
+
+	return append(cmds, build.Cmd{
+		Exec: []string{"sh", "-c", escape(script) + " >> {{.OutputDir}}/" + name},
+	})
+}
+
+func buildJob(n *node, nodes map[string]*node, goVersion string) *build.Job {
+	var deps []*node
+	var std []string
+	for _, imp := range n.pkg.Imports {
+		if dep, ok := nodes[imp]; ok {
+			deps = append(deps, dep)
+		} else if imp != "unsafe" {
+			std = append(std, imp)
+		}
+	}
+
+	job := &build.Job{
+		ID:   build.NewID(),
+		Name: "build " + n.pkg.ImportPath,
+		Cmds: importcfg("importcfg", goVersion, deps, n.pkg.ImportMap, std, false),
+	}
+
+	for _, dep := range deps {
+		job.Deps = append(job.Deps, dep.build.ID)
+	}
+
+	pkgPath := n.pkg.ImportPath
+	if n.pkg.Name == "main" {
+		pkgPath = "main"
+	}
+
+	compile := []string{
+		"go", "tool", "compile",
+		"-o", "{{.OutputDir}}/lib.a",
+		"-p", escape(pkgPath),
+		"-importcfg", "{{.OutputDir}}/importcfg",
+		"-trimpath", "{{.SourceDir}}",
+		"-pack",
+	}
+
+	if n.pkg.Module != nil && n.pkg.Module.GoVersion != "" {
+		compile = append(compile, "-lang=go"+n.pkg.Module.GoVersion)
+	}
+
+	for _, name := range n.pkg.GoFiles {
+		file := path.Join(n.dir, name)
+		job.Inputs = append(job.Inputs, file)
+		compile = append(compile, "{{.SourceDir}}/"+escape(file))
+	}
+
+	job.Cmds = append(job.Cmds, build.Cmd{Exec: compile})
+	return job
+}
+
+func linkJob(n *node, nodes map[string]*node, goVersion string) build.Job {
+	deps := closure(nodes, n.pkg.Imports)
+
+	std := []string{"runtime"}
+	for _, dep := range append([]*node{n}, deps...) {
+		for _, imp := range dep.pkg.Imports {
+			if _, ok := nodes[imp]; !ok && !slices.Contains(std, imp) {
+				std = append(std, imp)
+			}
+		}
+	}
+
+	job := build.Job{
+		ID:   build.NewID(),
+		Name: "link " + n.pkg.ImportPath,
+		Deps: []build.ID{n.build.ID},
+		Cmds: importcfg("importcfg.link", goVersion, deps, nil, std, true),
+	}
+
+	for _, dep := range deps {
+		job.Deps = append(job.Deps, dep.build.ID)
+	}
+
+	job.Cmds = append(job.Cmds, build.Cmd{
+		Exec: []string{
+			"go", "tool", "link",
+			"-o", "{{.OutputDir}}/" + escape(path.Base(n.pkg.ImportPath)),
+			"-importcfg", "{{.OutputDir}}/importcfg.link",
+			"-buildmode=exe",
+			depPath(n.build.ID) + "/lib.a",
+		},
+	})
+
+	return job
+}
+
+// goJob запускает команду go в директории с исходным кодом.
+//
+// Команда go сама собирает все зависимости пакета, поэтому джобу нужны исходники всего замыкания импортов.
+// Зависимость от джоба сборки пакета нужна только для того, чтобы не запускать go vet и go test на пакете,
+// который не компилируется.
+func goJob(tool string, n *node, modFiles []string, deps []*node) build.Job {
+	job := build.Job{
+		ID:     build.NewID(),
+		Name:   tool + " " + n.pkg.ImportPath,
+		Deps:   []build.ID{n.build.ID},
+		Inputs: slices.Clone(modFiles),
+		Cmds: []build.Cmd{
+			{
+				Exec:             []string{"go", tool, "./" + escape(n.dir)},
+				WorkingDirectory: "{{.SourceDir}}",
+			},
+		},
+	}
+
+	for _, dep := range append([]*node{n}, deps...) {
+		for _, name := range dep.pkg.GoFiles {
+			job.Inputs = append(job.Inputs, path.Join(dep.dir, name))
+		}
+	}
+
+	return job
+}
+
+// closure возвращает все пакеты графа, достижимые из imports.
+func closure(nodes map[string]*node, imports []string) []*node {
+	var deps []*node
+	visited := map[string]bool{}
+
+	var visit func(imports []string)
+	visit = func(imports []string) {
+		for _, imp := range imports {
+			n, ok := nodes[imp]
+			if !ok || visited[imp] {
+				continue
+			}
+
+			visited[imp] = true
+			deps = append(deps, n)
+			visit(n.pkg.Imports)
+		}
+	}
+
+	visit(imports)
+	return deps
+}
+
+func moduleFiles(root string) []string {
+	var files []string
+	for _, name := range []string{"go.mod", "go.sum", "vendor/modules.txt"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+func testdata(root, dir string) []string {
+	var files []string
+	_ = filepath.WalkDir(filepath.Join(root, dir, "testdata"), func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(root, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
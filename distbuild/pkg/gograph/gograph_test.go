@@ -0,0 +1,141 @@
+package gograph_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/gograph"
+)
+
+var testModule = map[string]string{
+	"go.mod": "module example.com/hello\n\ngo 1.24\n",
+	"greet/greet.go": `package greet
+
+func Greet(name string) string { return "Hello, " + name }
+`,
+	"greet/greet_test.go": `package greet
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGreet(t *testing.T) {
+	for _, name := range []string{"testdata/world.golden", "testdata/world_copy.golden"} {
+		golden, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Greet("world") != string(golden) {
+			t.Fail()
+		}
+	}
+}
+`,
+	"greet/testdata/world.golden":      "Hello, world",
+	"greet/testdata/world_copy.golden": "Hello, world",
+	"cmd/hello/main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/hello/greet"
+)
+
+func main() { fmt.Println(greet.Greet("distbuild")) }
+`,
+}
+
+// runGraph выполняет джобы графа локально, так же как это делал бы воркер.
+func runGraph(t *testing.T, graph *build.Graph, sourceDir string) map[build.ID]string {
+	outputs := map[build.ID]string{}
+
+	for _, job := range build.TopSort(graph.Jobs) {
+		ctx := build.JobContext{
+			SourceDir: sourceDir,
+			OutputDir: filepath.Join(t.TempDir(), job.ID.String()),
+			Deps:      map[build.ID]string{},
+		}
+		require.NoError(t, os.Mkdir(ctx.OutputDir, 0777))
+
+		for _, dep := range job.Deps {
+			ctx.Deps[dep] = outputs[dep]
+		}
+
+		for _, cmd := range job.Cmds {
+			rendered, err := cmd.Render(ctx)
+			require.NoError(t, err)
+
+			if rendered.CatOutput != "" {
+				require.NoError(t, os.WriteFile(rendered.CatOutput, []byte(rendered.CatTemplate), 0666))
+				continue
+			}
+
+			c := exec.Command(rendered.Exec[0], rendered.Exec[1:]...)
+			c.Dir = rendered.WorkingDirectory
+			output, err := c.CombinedOutput()
+			require.NoError(t, err, "job %q failed:\n%s", job.Name, output)
+		}
+
+		outputs[job.ID] = ctx.OutputDir
+	}
+
+	return outputs
+}
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles packages with the local go toolchain")
+	}
+
+	dir := t.TempDir()
+	for name, content := range testModule {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0777))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0666))
+	}
+
+	graph, err := gograph.Generate(context.Background(), gograph.Config{Dir: dir, Vet: true, Test: true})
+	require.NoError(t, err)
+	require.NoError(t, build.Validate(*graph))
+
+	jobs := map[string]build.Job{}
+	for _, job := range graph.Jobs {
+		jobs[job.Name] = job
+	}
+
+	require.Len(t, jobs, 6)
+	require.Contains(t, jobs, "build example.com/hello/greet")
+	require.Contains(t, jobs, "build example.com/hello/cmd/hello")
+	require.Contains(t, jobs, "link example.com/hello/cmd/hello")
+	require.Contains(t, jobs, "vet example.com/hello/greet")
+	require.Contains(t, jobs, "vet example.com/hello/cmd/hello")
+	require.Contains(t, jobs, "test example.com/hello/greet")
+	require.NotContains(t, jobs, "test example.com/hello/cmd/hello")
+	require.Len(t, graph.SourceCopies, 1)
+
+	again, err := gograph.Generate(context.Background(), gograph.Config{Dir: dir, Vet: true, Test: true})
+	require.NoError(t, err)
+	require.Equal(t, graph, again)
+
+	outputs := runGraph(t, graph, dir)
+
+	binary := filepath.Join(outputs[jobs["link example.com/hello/cmd/hello"].ID], "hello")
+	output, err := exec.Command(binary).Output()
+	require.NoError(t, err)
+	require.Equal(t, "Hello, distbuild\n", string(output))
+}
+
+func TestUnsupported(t *testing.T) {
+	pkgs := []*gograph.Package{
+		{Dir: "/tmp/elsewhere", ImportPath: "example.com/other", Name: "other", GoFiles: []string{"other.go"}},
+	}
+
+	_, err := gograph.NewGraph(pkgs, "go1.24", gograph.Config{Dir: "/tmp/module"})
+	require.ErrorIs(t, err, gograph.ErrUnsupported)
+}
@@ -0,0 +1,244 @@
+//go:build linux
+
+package jobexec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+type process struct {
+	cmd    *exec.Cmd
+	limits build.Limits
+
+	cgroup   string
+	cgroupFD *os.File
+
+	sandboxRoot string
+}
+
+func (r *Runner) start(cmd *exec.Cmd, limits build.Limits, sandbox *Sandbox) (*process, error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	p := &process{cmd: cmd, limits: limits}
+
+	if r.cgroupDir != "" && limits.Memory > 0 {
+		if err := p.createCgroup(r.cgroupDir); err != nil {
+			p.cleanup()
+			return nil, err
+		}
+
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(p.cgroupFD.Fd())
+	}
+
+	rlimits := p.rlimits()
+
+	var err error
+	switch {
+	case sandbox != nil:
+		if p.sandboxRoot, err = os.MkdirTemp("", "sandbox-"); err == nil {
+			err = wrapSandbox(cmd, sandbox, p.sandboxRoot, rlimits)
+		}
+	case len(rlimits) != 0:
+		err = wrapRlimits(cmd, rlimits)
+	}
+
+	if err != nil {
+		p.cleanup()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		p.cleanup()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *process) createCgroup(root string) error {
+	dir, err := os.MkdirTemp(root, "job-")
+	if err != nil {
+		return err
+	}
+	p.cgroup = dir
+
+	memoryMax := strconv.FormatInt(p.limits.Memory, 10)
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(memoryMax), 0); err != nil {
+		return err
+	}
+
+	// memory.swap.max отсутствует, если в системе выключен swap.
+	_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0)
+
+	p.cgroupFD, err = os.Open(dir)
+	return err
+}
+
+type rlimit struct {
+	Resource int
+	Cur, Max uint64
+}
+
+// rlimits возвращает лимиты, которые нужно выставить процессу до запуска команды.
+//
+// Лимиты наследуются всеми потомками процесса.
+func (p *process) rlimits() []rlimit {
+	var rlimits []rlimit
+
+	if p.cgroup == "" && p.limits.Memory > 0 {
+		limit := uint64(p.limits.Memory)
+		rlimits = append(rlimits, rlimit{Resource: unix.RLIMIT_AS, Cur: limit, Max: limit})
+	}
+
+	if p.limits.CPUTime > 0 {
+		// По мягкому лимиту процесс получает SIGXCPU, по жёсткому - SIGKILL.
+		seconds := cpuLimitSeconds(p.limits)
+		rlimits = append(rlimits, rlimit{Resource: unix.RLIMIT_CPU, Cur: seconds, Max: seconds + 1})
+	}
+
+	return rlimits
+}
+
+func setRlimits(rlimits []rlimit) error {
+	for _, l := range rlimits {
+		if err := unix.Setrlimit(l.Resource, &unix.Rlimit{Cur: l.Cur, Max: l.Max}); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", l.Resource, err)
+		}
+	}
+	return nil
+}
+
+type rlimitSpec struct {
+	Rlimits []rlimit
+	Path    string
+	Args    []string
+}
+
+// wrapRlimits подменяет cmd на запуск /proc/self/exe, который выставляет rlimits и запускает исходную команду.
+//
+// Если выставить лимиты через prlimit после cmd.Start, команда и её потомки, созданные до этого момента,
+// успеют поработать без ограничений.
+func wrapRlimits(cmd *exec.Cmd, rlimits []rlimit) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+
+	specJSON, err := json.Marshal(rlimitSpec{Rlimits: rlimits, Path: cmd.Path, Args: cmd.Args})
+	if err != nil {
+		return err
+	}
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{rlimitInitArg, string(specJSON)}
+	return nil
+}
+
+func rlimitInit(specJSON string) {
+	var spec rlimitSpec
+	err := json.Unmarshal([]byte(specJSON), &spec)
+	if err == nil {
+		err = setRlimits(spec.Rlimits)
+	}
+
+	if err == nil {
+		err = unix.Exec(spec.Path, spec.Args, os.Environ())
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "rlimit: %v\n", err)
+	os.Exit(sandboxExitCode)
+}
+
+func cpuLimitSeconds(limits build.Limits) uint64 {
+	return uint64((limits.CPUTime + time.Second - 1) / time.Second)
+}
+
+func (p *process) kill() {
+	if p.cmd.Process == nil {
+		return
+	}
+
+	if p.cgroup != "" {
+		_ = os.WriteFile(filepath.Join(p.cgroup, "cgroup.kill"), []byte("1"), 0)
+	}
+
+	_ = syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+func (p *process) reason(cmd *exec.Cmd) api.FailureReason {
+	if p.cgroup != "" && p.oomKilled() {
+		return api.FailureOOM
+	}
+
+	ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || p.limits.CPUTime == 0 {
+		return ""
+	}
+
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		return api.FailureCPULimit
+	case syscall.SIGKILL:
+		// Процесс, игнорирующий SIGXCPU, убивается по жёсткому лимиту.
+		cpuTime := cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		if cpuTime >= time.Duration(cpuLimitSeconds(p.limits))*time.Second {
+			return api.FailureCPULimit
+		}
+	}
+
+	return ""
+}
+
+func (p *process) oomKilled() bool {
+	f, err := os.Open(filepath.Join(p.cgroup, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, value, _ := strings.Cut(s.Text(), " ")
+		if key != "oom_kill" {
+			continue
+		}
+
+		n, _ := strconv.Atoi(value)
+		return n > 0
+	}
+
+	return false
+}
+
+func (p *process) cleanup() {
+	if p.cgroupFD != nil {
+		_ = p.cgroupFD.Close()
+	}
+
+	if p.cgroup != "" {
+		// Убиваем потомков, которые успели сменить группу процессов.
+		_ = os.WriteFile(filepath.Join(p.cgroup, "cgroup.kill"), []byte("1"), 0)
+		_ = os.Remove(p.cgroup)
+	}
+
+	if p.sandboxRoot != "" {
+		_ = os.Remove(p.sandboxRoot)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+
+package jobexec
+
+import (
+	"os/exec"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// На остальных платформах поддерживается только build.Limits.Timeout.
+type process struct {
+	cmd *exec.Cmd
+}
+
+func (r *Runner) start(cmd *exec.Cmd, limits build.Limits, sandbox *Sandbox) (*process, error) {
+	if sandbox != nil {
+		return nil, ErrSandboxUnsupported
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &process{cmd: cmd}, nil
+}
+
+func (p *process) kill() {
+	_ = p.cmd.Process.Kill()
+}
+
+func (p *process) reason(cmd *exec.Cmd) api.FailureReason {
+	return ""
+}
+
+func (p *process) cleanup() {}
+
+func sandboxInit(specJSON string) {}
+
+func rlimitInit(specJSON string) {}
@@ -0,0 +1,84 @@
+package jobexec
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrTimeout = errors.New("job timeout exceeded")
+
+// Runner запускает процессы джобов с ограничениями из build.Limits.
+type Runner struct {
+	cgroupDir string
+}
+
+// NewRunner создаёт Runner.
+//
+// cgroupDir задаёт директорию cgroups v2, делегированную воркеру. Внутри неё Runner создаёт
+// отдельную группу на каждый процесс и ограничивает память через memory.max. В этом случае
+// превышение лимита памяти определяется надёжно и сообщается как api.FailureOOM.
+//
+// Если cgroupDir пустой, память ограничивается через RLIMIT_AS. Процесс, которому не хватило памяти,
+// завершается с обычной ошибкой, и причина падения остаётся неизвестной.
+func NewRunner(cgroupDir string) *Runner {
+	return &Runner{cgroupDir: cgroupDir}
+}
+
+// WithLimits возвращает контекст, ограничивающий время работы всего джоба.
+//
+// Все команды джоба нужно запускать с этим контекстом, тогда Run сможет отличить
+// истечение build.Limits.Timeout от отмены джоба.
+func WithLimits(ctx context.Context, limits build.Limits) (context.Context, context.CancelFunc) {
+	if limits.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeoutCause(ctx, limits.Timeout, ErrTimeout)
+}
+
+// Run запускает cmd и дожидается его завершения.
+//
+// Если sandbox != nil, команда запускается внутри песочницы. Песочница поддерживается только в Linux.
+//
+// При отмене ctx Run убивает все процессы, порождённые cmd. Если процесс завершился из-за
+// нарушения ограничений или отмены ctx, Run возвращает соответствующую причину вместе с ошибкой.
+func (r *Runner) Run(ctx context.Context, cmd *exec.Cmd, limits build.Limits, sandbox *Sandbox) (api.FailureReason, error) {
+	p, err := r.start(cmd, limits, sandbox)
+	if err != nil {
+		return "", err
+	}
+	defer p.cleanup()
+
+	waitDone := make(chan struct{})
+	killed := make(chan struct{})
+	go func() {
+		defer close(killed)
+
+		select {
+		case <-ctx.Done():
+			p.kill()
+		case <-waitDone:
+		}
+	}()
+
+	err = cmd.Wait()
+	close(waitDone)
+	<-killed
+
+	if err == nil {
+		return "", nil
+	}
+
+	switch {
+	case ctx.Err() != nil && errors.Is(context.Cause(ctx), ErrTimeout):
+		return api.FailureTimeout, err
+	case ctx.Err() != nil:
+		return api.FailureCanceled, err
+	}
+
+	return p.reason(cmd), err
+}
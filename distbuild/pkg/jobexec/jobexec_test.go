@@ -0,0 +1,75 @@
+package jobexec_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/jobexec"
+)
+
+func run(ctx context.Context, limits build.Limits, args ...string) (api.FailureReason, error) {
+	ctx, cancel := jobexec.WithLimits(ctx, limits)
+	defer cancel()
+
+	return jobexec.NewRunner("").Run(ctx, exec.Command(args[0], args[1:]...), limits, nil)
+}
+
+func TestRunSuccess(t *testing.T) {
+	reason, err := run(context.Background(), build.Limits{Timeout: time.Minute}, "true")
+	require.NoError(t, err)
+	require.Empty(t, reason)
+
+	reason, err = run(context.Background(), build.Limits{}, "false")
+	require.Error(t, err)
+	require.Empty(t, reason)
+}
+
+func TestRunTimeout(t *testing.T) {
+	start := time.Now()
+
+	// Внук процесса держит stdout открытым, поэтому убить нужно всю группу процессов.
+	reason, err := run(context.Background(), build.Limits{Timeout: 100 * time.Millisecond}, "bash", "-c", "sleep 10 & sleep 10")
+	require.Error(t, err)
+	require.Equal(t, api.FailureTimeout, reason)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	reason, err := run(ctx, build.Limits{Timeout: time.Minute}, "sleep", "10")
+	require.Error(t, err)
+	require.Equal(t, api.FailureCanceled, reason)
+}
+
+func TestRunCPULimit(t *testing.T) {
+	reason, err := run(context.Background(), build.Limits{CPUTime: time.Second, Timeout: time.Minute}, "bash", "-c", "while :; do :; done")
+	require.Error(t, err)
+	require.Equal(t, api.FailureCPULimit, reason)
+}
+
+func TestRunLimitsBeforeFork(t *testing.T) {
+	limits := build.Limits{CPUTime: 2 * time.Second, Memory: 1 << 30, Timeout: time.Minute}
+
+	ctx, cancel := jobexec.WithLimits(context.Background(), limits)
+	defer cancel()
+
+	// Оболочка сразу создаёт потомка, поэтому лимиты должны быть выставлены ещё до запуска команды.
+	var stdout strings.Builder
+	cmd := exec.Command("sh", "-c", "cat /proc/self/limits; true")
+	cmd.Stdout = &stdout
+
+	_, err := jobexec.NewRunner("").Run(ctx, cmd, limits, nil)
+	require.NoError(t, err)
+
+	require.Regexp(t, `Max cpu time\s+2\s+3\s+seconds`, stdout.String())
+	require.Regexp(t, `Max address space\s+1073741824\s+1073741824\s+bytes`, stdout.String())
+}
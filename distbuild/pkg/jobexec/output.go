@@ -0,0 +1,120 @@
+package jobexec
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultOutputLimit ограничивает суммарный размер stdout и stderr одного джоба.
+const DefaultOutputLimit = 4 << 20
+
+// Output собирает stdout и stderr джоба и отдаёт их порциями через Flush, а целиком - через Result.
+//
+// Всё, что джоб выводит сверх limit, выбрасывается. Вместо отброшенного вывода в поток,
+// который переполнил лимит, один раз дописывается строка-маркер.
+type Output struct {
+	limit int
+
+	mu        sync.Mutex
+	written   int
+	truncated bool
+
+	// stdout и stderr хранят весь вывод, а flushedStdout и flushedStderr - сколько байт уже отдал Flush.
+	stdout, stderr               []byte
+	flushedStdout, flushedStderr int
+}
+
+func NewOutput(limit int) *Output {
+	return &Output{limit: limit}
+}
+
+// TruncationMarker возвращает маркер, который Output дописывает на месте отброшенного вывода.
+func TruncationMarker(limit int) string {
+	return fmt.Sprintf("\n... output truncated: job exceeded the limit of %d bytes ...\n", limit)
+}
+
+type outputStream struct {
+	o      *Output
+	stderr bool
+}
+
+func (s outputStream) Write(p []byte) (int, error) {
+	s.o.write(p, s.stderr)
+
+	// Ошибка записи заставила бы exec.Cmd оборвать копирование, и процесс получил бы SIGPIPE.
+	return len(p), nil
+}
+
+// Stdout возвращает writer, который нужно передать в exec.Cmd.Stdout.
+func (o *Output) Stdout() io.Writer {
+	return outputStream{o: o}
+}
+
+// Stderr возвращает writer, который нужно передать в exec.Cmd.Stderr.
+func (o *Output) Stderr() io.Writer {
+	return outputStream{o: o, stderr: true}
+}
+
+func (o *Output) write(p []byte, stderr bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.truncated {
+		return
+	}
+
+	buf := &o.stdout
+	if stderr {
+		buf = &o.stderr
+	}
+
+	if free := o.limit - o.written; len(p) > free {
+		*buf = append(*buf, p[:free]...)
+		*buf = append(*buf, TruncationMarker(o.limit)...)
+
+		o.written = o.limit
+		o.truncated = true
+		return
+	}
+
+	*buf = append(*buf, p...)
+	o.written += len(p)
+}
+
+// Flush возвращает вывод, накопленный с предыдущего вызова Flush.
+func (o *Output) Flush() (stdout, stderr []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stdout = clip(o.stdout[o.flushedStdout:])
+	stderr = clip(o.stderr[o.flushedStderr:])
+	o.flushedStdout, o.flushedStderr = len(o.stdout), len(o.stderr)
+	return
+}
+
+// Result возвращает весь вывод джоба с учётом лимита, независимо от вызовов Flush.
+//
+// Воркер кладёт его в api.JobResult, когда джоб завершился.
+func (o *Output) Result() (stdout, stderr []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return clip(o.stdout), clip(o.stderr)
+}
+
+// clip не даёт вызывающему дописать в буфер Output через append.
+func clip(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b[:len(b):len(b)]
+}
+
+// Truncated сообщает, был ли отброшен хотя бы один байт вывода.
+func (o *Output) Truncated() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.truncated
+}
@@ -0,0 +1,73 @@
+package jobexec_test
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/jobexec"
+)
+
+func TestOutputFlush(t *testing.T) {
+	out := jobexec.NewOutput(1024)
+
+	_, _ = fmt.Fprint(out.Stdout(), "hello ")
+	_, _ = fmt.Fprint(out.Stderr(), "warning")
+
+	stdout, stderr := out.Flush()
+	require.Equal(t, "hello ", string(stdout))
+	require.Equal(t, "warning", string(stderr))
+
+	_, _ = fmt.Fprint(out.Stdout(), "world")
+
+	stdout, stderr = out.Flush()
+	require.Equal(t, "world", string(stdout))
+	require.Empty(t, stderr)
+	require.False(t, out.Truncated())
+
+	stdout, stderr = out.Flush()
+	require.Empty(t, stdout)
+	require.Empty(t, stderr)
+
+	stdout, stderr = out.Result()
+	require.Equal(t, "hello world", string(stdout))
+	require.Equal(t, "warning", string(stderr))
+}
+
+func TestOutputTruncation(t *testing.T) {
+	out := jobexec.NewOutput(8)
+
+	n, err := out.Stdout().Write([]byte("12345"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = out.Stderr().Write([]byte("abcdef"))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+
+	_, _ = out.Stdout().Write([]byte("dropped"))
+
+	stdout, stderr := out.Flush()
+	require.Equal(t, "12345", string(stdout))
+	require.Equal(t, "abc"+jobexec.TruncationMarker(8), string(stderr))
+	require.True(t, out.Truncated())
+
+	stdout, stderr = out.Result()
+	require.Equal(t, "12345", string(stdout))
+	require.Equal(t, "abc"+jobexec.TruncationMarker(8), string(stderr))
+}
+
+func TestOutputCommand(t *testing.T) {
+	out := jobexec.NewOutput(jobexec.DefaultOutputLimit)
+
+	cmd := exec.Command("bash", "-c", "echo out; echo err >&2")
+	cmd.Stdout = out.Stdout()
+	cmd.Stderr = out.Stderr()
+	require.NoError(t, cmd.Run())
+
+	stdout, stderr := out.Flush()
+	require.Equal(t, "out\n", string(stdout))
+	require.Equal(t, "err\n", string(stderr))
+}
@@ -0,0 +1,79 @@
+package jobexec
+
+import (
+	"errors"
+	"os"
+	"sort"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrSandboxUnsupported = errors.New("sandbox is not supported on this platform")
+
+// DefaultSystemDirs перечисляет директории хоста, которые видны внутри песочницы только на чтение.
+//
+// Без них внутри песочницы не получится запустить ни одну динамически слинкованную программу.
+var DefaultSystemDirs = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc"}
+
+// Sandbox описывает изолированное окружение, в котором запускаются команды одного джоба.
+//
+// Внутри песочницы видны только SystemDirs, ReadOnly и ReadWrite, а также пустые /tmp, /proc и /dev.
+// Обращение к любому другому пути заканчивается ошибкой ENOENT, поэтому команда, читающая
+// необъявленный вход, падает и роняет джоб.
+type Sandbox struct {
+	// SystemDirs задаёт системные директории, доступные только на чтение.
+	SystemDirs []string
+
+	// ReadOnly задаёт директории джоба, доступные только на чтение.
+	ReadOnly []string
+
+	// ReadWrite задаёт директории джоба, доступные на запись.
+	ReadWrite []string
+
+	// Network разрешает джобу доступ к сети. По умолчанию джоб видит только выключенный loopback.
+	Network bool
+}
+
+// NewSandbox возвращает песочницу для джоба с контекстом ctx.
+//
+// Джоб может читать SourceDir и артефакты своих зависимостей, а писать только в OutputDir.
+func NewSandbox(ctx build.JobContext) *Sandbox {
+	s := &Sandbox{
+		SystemDirs: DefaultSystemDirs,
+		ReadWrite:  []string{ctx.OutputDir},
+	}
+
+	if ctx.SourceDir != "" {
+		s.ReadOnly = append(s.ReadOnly, ctx.SourceDir)
+	}
+
+	for _, dir := range ctx.Deps {
+		s.ReadOnly = append(s.ReadOnly, dir)
+	}
+	sort.Strings(s.ReadOnly)
+
+	return s
+}
+
+const (
+	initArg       = "jobexec-sandbox-init"
+	rlimitInitArg = "jobexec-rlimit-init"
+)
+
+// Init доделывает настройку песочницы или лимитов, если текущий процесс был запущен Runner-ом.
+//
+// Runner запускает команды в песочнице и команды с ограничениями Memory и CPUTime через /proc/self/exe,
+// чтобы выставить лимиты до запуска команды. Поэтому Init нужно вызвать в самом начале main любой
+// программы, использующей Runner. В обычном запуске Init ничего не делает.
+func Init() {
+	if len(os.Args) != 2 {
+		return
+	}
+
+	switch os.Args[0] {
+	case initArg:
+		sandboxInit(os.Args[1])
+	case rlimitInitArg:
+		rlimitInit(os.Args[1])
+	}
+}
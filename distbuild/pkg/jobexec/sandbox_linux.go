@@ -0,0 +1,278 @@
+//go:build linux
+
+package jobexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxExitCode возвращается, если не удалось подготовить песочницу или выставить лимиты.
+const sandboxExitCode = 125
+
+type sandboxSpec struct {
+	Sandbox
+
+	Root    string
+	Rlimits []rlimit
+	Path    string
+	Args    []string
+	Dir     string
+	Env     []string
+}
+
+// wrapSandbox подменяет cmd на запуск /proc/self/exe в новых namespace-ах.
+//
+// Дочерний процесс попадает в Init, монтирует директории из s внутрь root, выставляет rlimits и только
+// после этого запускает исходную команду.
+func wrapSandbox(cmd *exec.Cmd, s *Sandbox, root string, rlimits []rlimit) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+
+	spec := sandboxSpec{
+		Sandbox: *s,
+		Root:    root,
+		Rlimits: rlimits,
+		Path:    cmd.Path,
+		Args:    cmd.Args,
+		Dir:     cmd.Dir,
+		Env:     cmd.Environ(),
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = []string{initArg, string(specJSON)}
+	cmd.Dir = ""
+	cmd.Env = []string{}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !s.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	cmd.SysProcAttr.Cloneflags |= uintptr(flags)
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return nil
+}
+
+func sandboxInit(specJSON string) {
+	runtime.LockOSThread()
+
+	var spec sandboxSpec
+	err := json.Unmarshal([]byte(specJSON), &spec)
+	if err == nil {
+		err = setupSandbox(&spec)
+	}
+
+	if err == nil {
+		err = setRlimits(spec.Rlimits)
+	}
+
+	if err == nil {
+		err = unix.Exec(spec.Path, spec.Args, spec.Env)
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(sandboxExitCode)
+}
+
+type mount struct {
+	path     string
+	readOnly bool
+}
+
+func setupSandbox(spec *sandboxSpec) error {
+	root := spec.Root
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make / private: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	// Директории джоба часто лежат внутри /tmp, поэтому /tmp монтируется до них.
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	var mounts []mount
+	for _, dir := range spec.SystemDirs {
+		mounts = append(mounts, mount{path: dir, readOnly: true})
+	}
+	for _, dir := range spec.ReadOnly {
+		mounts = append(mounts, mount{path: dir, readOnly: true})
+	}
+	for _, dir := range spec.ReadWrite {
+		mounts = append(mounts, mount{path: dir})
+	}
+
+	// Родительские директории монтируются раньше вложенных.
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return mounts[i].path < mounts[j].path
+	})
+
+	for _, m := range mounts {
+		if err := bindMount(root, m); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "proc"), 0755); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	for _, dev := range []string{"null", "zero", "full", "random", "urandom"} {
+		if err := bindMount(root, mount{path: filepath.Join("/dev", dev)}); err != nil {
+			return err
+		}
+	}
+
+	if err := pivotRoot(root); err != nil {
+		return err
+	}
+
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount / read-only: %w", err)
+	}
+
+	if spec.Dir != "" {
+		if err := os.Chdir(spec.Dir); err != nil {
+			return err
+		}
+	}
+
+	return dropCapabilities()
+}
+
+func bindMount(root string, m mount) error {
+	st, err := os.Lstat(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, m.path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case st.Mode()&os.ModeSymlink != 0:
+		// Например, /lib -> usr/lib в дистрибутивах с объединённым /usr.
+		link, err := os.Readlink(m.path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+
+	case st.IsDir():
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+
+	default:
+		if err := os.WriteFile(target, nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Mount(m.path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", m.path, err)
+	}
+
+	if !m.readOnly {
+		return nil
+	}
+
+	// Внутри user namespace нельзя снять флаги, унаследованные от исходной точки монтирования.
+	var fs unix.Statfs_t
+	if err := unix.Statfs(target, &fs); err != nil {
+		return err
+	}
+
+	flags := uintptr(unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY)
+	for stFlag, msFlag := range map[int64]uintptr{
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if fs.Flags&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", m.path, err)
+	}
+
+	return nil
+}
+
+func pivotRoot(root string) error {
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount old root: %w", err)
+	}
+
+	return os.Remove("/.oldroot")
+}
+
+// dropCapabilities очищает bounding set, так что команда джоба запускается без привилегий
+// даже внутри своего user namespace.
+func dropCapabilities() error {
+	lastCap := unix.CAP_LAST_CAP
+	if b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			lastCap = n
+		}
+	}
+
+	for c := 0; c <= lastCap; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil {
+			return fmt.Errorf("drop capability %d: %w", c, err)
+		}
+	}
+
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
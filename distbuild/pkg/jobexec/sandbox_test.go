@@ -0,0 +1,105 @@
+//go:build linux
+
+package jobexec_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/jobexec"
+)
+
+func TestMain(m *testing.M) {
+	jobexec.Init()
+	os.Exit(m.Run())
+}
+
+type sandboxEnv struct {
+	ctx    build.JobContext
+	secret string
+}
+
+func newSandboxEnv(t *testing.T) *sandboxEnv {
+	if err := exec.Command("unshare", "--user", "--map-root-user", "--mount", "true").Run(); err != nil {
+		t.Skipf("user namespaces are not available: %v", err)
+	}
+
+	root := t.TempDir()
+
+	env := &sandboxEnv{
+		ctx: build.JobContext{
+			SourceDir: filepath.Join(root, "src"),
+			OutputDir: filepath.Join(root, "out"),
+			Deps:      map[build.ID]string{{'a'}: filepath.Join(root, "dep")},
+		},
+		secret: filepath.Join(root, "secret.txt"),
+	}
+
+	require.NoError(t, os.MkdirAll(env.ctx.SourceDir, 0777))
+	require.NoError(t, os.MkdirAll(env.ctx.OutputDir, 0777))
+	require.NoError(t, os.MkdirAll(env.ctx.Deps[build.ID{'a'}], 0777))
+
+	require.NoError(t, os.WriteFile(filepath.Join(env.ctx.SourceDir, "a.txt"), []byte("foo"), 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(env.ctx.Deps[build.ID{'a'}], "b.txt"), []byte("bar"), 0666))
+	require.NoError(t, os.WriteFile(env.secret, []byte("secret"), 0666))
+
+	return env
+}
+
+func (e *sandboxEnv) run(script string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = e.ctx.SourceDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	_, err := jobexec.NewRunner("").Run(context.Background(), cmd, build.Limits{}, jobexec.NewSandbox(e.ctx))
+	return stdout.String(), err
+}
+
+func TestSandboxDeclaredFiles(t *testing.T) {
+	env := newSandboxEnv(t)
+
+	out, err := env.run("cat a.txt " + env.ctx.Deps[build.ID{'a'}] + "/b.txt > " + env.ctx.OutputDir + "/c.txt && cat " + env.ctx.OutputDir + "/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "foobar", out)
+
+	content, err := os.ReadFile(filepath.Join(env.ctx.OutputDir, "c.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), content)
+}
+
+func TestSandboxUndeclaredFiles(t *testing.T) {
+	env := newSandboxEnv(t)
+
+	_, err := env.run("cat " + env.secret)
+	require.Error(t, err)
+
+	_, err = env.run("echo x > a.txt")
+	require.Error(t, err)
+
+	_, err = env.run("echo x > " + env.ctx.Deps[build.ID{'a'}] + "/b.txt")
+	require.Error(t, err)
+
+	_, err = env.run("touch /x")
+	require.Error(t, err)
+
+	_, err = env.run("echo x > /tmp/x")
+	require.NoError(t, err)
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	env := newSandboxEnv(t)
+
+	out, err := env.run("cat /proc/net/dev | tail -n +3 | cut -d: -f1 | tr -d ' '")
+	require.NoError(t, err)
+	require.Equal(t, "lo\n", out)
+}
@@ -0,0 +1,231 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrClosed = errors.New("journal is closed")
+
+const (
+	fileName    = "journal"
+	tmpFileName = "journal.tmp"
+)
+
+// Entry описывает одну запись журнала.
+//
+// Как и в api.StatusUpdate, реальный тип записи определяется тем, какое поле структуры заполнено.
+type Entry struct {
+	BuildStarted     *BuildStarted
+	JobFinished      *JobFinished
+	ArtifactsAdded   *ArtifactsAdded
+	ArtifactsRemoved *ArtifactsRemoved
+	WorkerLost       *WorkerLost
+	BuildFinished    *BuildFinished
+}
+
+// BuildStarted записывается, когда координатор принял новую сборку.
+type BuildStarted struct {
+	ID    build.ID
+	Graph build.Graph
+
+	// User совпадает с api.BuildRequest.User.
+	User string
+}
+
+// JobFinished записывается, когда воркер прислал в heartbeat результат джоба.
+type JobFinished struct {
+	WorkerID api.WorkerID
+	Result   api.JobResult
+}
+
+// ArtifactsAdded записывается, когда воркер сообщил о новых артефактах в своём кеше.
+type ArtifactsAdded struct {
+	WorkerID  api.WorkerID
+	Artifacts []build.ID
+}
+
+// ArtifactsRemoved записывается, когда воркер сообщил об удалении артефактов из своего кеша.
+type ArtifactsRemoved struct {
+	WorkerID  api.WorkerID
+	Artifacts []build.ID
+}
+
+// WorkerLost записывается, когда координатор посчитал воркер мёртвым и забыл его артефакты.
+type WorkerLost struct {
+	WorkerID api.WorkerID
+}
+
+// BuildFinished записывается, когда сборка завершилась успешно или с ошибкой.
+type BuildFinished struct {
+	ID build.ID
+}
+
+// Journal реализует write-ahead log состояния координатора.
+//
+// Каждая запись дописывается в конец файла в виде одной строки json и сбрасывается на диск
+// до того, как Append вернёт управление.
+type Journal struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// Open открывает журнал внутри rootDir и восстанавливает по нему состояние координатора.
+//
+// Недописанная последняя запись, оставшаяся после падения процесса, отбрасывается.
+// После восстановления журнал переписывается в компактном виде, так что записи
+// о завершённых сборках не накапливаются между перезапусками.
+func Open(rootDir string) (*Journal, *State, error) {
+	if err := os.MkdirAll(rootDir, 0777); err != nil {
+		return nil, nil, err
+	}
+
+	path := filepath.Join(rootDir, fileName)
+
+	state := NewState()
+	if err := replay(path, state); err != nil {
+		return nil, nil, err
+	}
+
+	tmpPath := filepath.Join(rootDir, tmpFileName)
+	if err := writeSnapshot(tmpPath, state); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, nil, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, nil, err
+	}
+
+	// Без fsync директории переименование может потеряться при падении машины.
+	if err := syncDir(rootDir); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Journal{f: f}, state, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return d.Sync()
+}
+
+func replay(path string, state *State) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Запись без перевода строки не была сброшена на диск целиком.
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("corrupted journal %s: %w", path, err)
+		}
+
+		state.Apply(&e)
+	}
+}
+
+func writeSnapshot(path string, state *State) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, e := range state.Snapshot() {
+		line, err := encode(e)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func encode(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Append дописывает запись в журнал.
+func (j *Journal) Append(e *Entry) error {
+	line, err := encode(e)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return ErrClosed
+	}
+
+	if _, err := j.f.Write(line); err != nil {
+		return err
+	}
+
+	return j.f.Sync()
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return nil
+	}
+
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
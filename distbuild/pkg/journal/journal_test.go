@@ -0,0 +1,135 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+)
+
+var testGraph = build.Graph{
+	SourceFiles: map[build.ID]string{{'s'}: "a.txt"},
+	Jobs: []build.Job{
+		{ID: build.ID{'a'}, Name: "write"},
+		{ID: build.ID{'b'}, Name: "cat", Deps: []build.ID{{'a'}}},
+	},
+}
+
+func TestJournalRestore(t *testing.T) {
+	root := t.TempDir()
+
+	j, state, err := journal.Open(root)
+	require.NoError(t, err)
+	require.Empty(t, state.Builds)
+
+	buildA, buildB := build.ID{'x'}, build.ID{'y'}
+
+	require.NoError(t, j.Append(&journal.Entry{BuildStarted: &journal.BuildStarted{ID: buildA, Graph: testGraph, User: "alice"}}))
+	require.NoError(t, j.Append(&journal.Entry{BuildStarted: &journal.BuildStarted{ID: buildB, Graph: testGraph}}))
+	require.NoError(t, j.Append(&journal.Entry{JobFinished: &journal.JobFinished{
+		WorkerID: "w0",
+		Result:   api.JobResult{ID: build.ID{'a'}, Stdout: []byte("OK")},
+	}}))
+	require.NoError(t, j.Append(&journal.Entry{ArtifactsAdded: &journal.ArtifactsAdded{
+		WorkerID:  "w1",
+		Artifacts: []build.ID{{'a'}, {'c'}},
+	}}))
+	require.NoError(t, j.Append(&journal.Entry{ArtifactsRemoved: &journal.ArtifactsRemoved{
+		WorkerID:  "w1",
+		Artifacts: []build.ID{{'c'}},
+	}}))
+	require.NoError(t, j.Append(&journal.Entry{BuildFinished: &journal.BuildFinished{ID: buildB}}))
+	require.NoError(t, j.Close())
+
+	require.ErrorIs(t, j.Append(&journal.Entry{}), journal.ErrClosed)
+
+	for range 2 {
+		j, state, err = journal.Open(root)
+		require.NoError(t, err)
+		require.NoError(t, j.Close())
+
+		require.Len(t, state.Builds, 1)
+		require.Equal(t, testGraph, *state.Builds[buildA])
+		require.Equal(t, map[build.ID]string{buildA: "alice"}, state.Users)
+
+		require.Len(t, state.Results, 1)
+		require.Equal(t, []byte("OK"), state.Results[build.ID{'a'}].Stdout)
+
+		require.Equal(t, map[api.WorkerID]struct{}{"w0": {}, "w1": {}}, state.Artifacts[build.ID{'a'}])
+		require.NotContains(t, state.Artifacts, build.ID{'c'})
+	}
+}
+
+func TestJournalTornWrite(t *testing.T) {
+	root := t.TempDir()
+
+	j, _, err := journal.Open(root)
+	require.NoError(t, err)
+	require.NoError(t, j.Append(&journal.Entry{BuildStarted: &journal.BuildStarted{ID: build.ID{'x'}, Graph: testGraph}}))
+	require.NoError(t, j.Close())
+
+	f, err := os.OpenFile(filepath.Join(root, "journal"), os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"BuildFinished":{"ID":"78000`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, state, err := journal.Open(root)
+	require.NoError(t, err)
+	defer j.Close()
+
+	require.Contains(t, state.Builds, build.ID{'x'})
+}
+
+func TestJournalDropsFinishedResults(t *testing.T) {
+	state := journal.NewState()
+	state.Apply(&journal.Entry{BuildStarted: &journal.BuildStarted{ID: build.ID{'x'}, Graph: testGraph}})
+	state.Apply(&journal.Entry{JobFinished: &journal.JobFinished{WorkerID: "w0", Result: api.JobResult{ID: build.ID{'a'}}}})
+	state.Apply(&journal.Entry{BuildFinished: &journal.BuildFinished{ID: build.ID{'x'}}})
+
+	restored := journal.NewState()
+	for _, e := range state.Snapshot() {
+		restored.Apply(e)
+	}
+
+	require.Empty(t, restored.Builds)
+	require.Empty(t, restored.Results)
+	require.Contains(t, restored.Artifacts, build.ID{'a'})
+}
+
+func TestJournalFailedJobHasNoArtifact(t *testing.T) {
+	failed := "exit status 1"
+
+	state := journal.NewState()
+	state.Apply(&journal.Entry{JobFinished: &journal.JobFinished{
+		WorkerID: "w0",
+		Result:   api.JobResult{ID: build.ID{'a'}, ExitCode: 1, Error: &failed},
+	}})
+
+	require.Contains(t, state.Results, build.ID{'a'})
+	require.Empty(t, state.Artifacts)
+}
+
+func TestJournalWorkerLost(t *testing.T) {
+	root := t.TempDir()
+
+	j, _, err := journal.Open(root)
+	require.NoError(t, err)
+	require.NoError(t, j.Append(&journal.Entry{ArtifactsAdded: &journal.ArtifactsAdded{WorkerID: "w0", Artifacts: []build.ID{{'a'}, {'b'}}}}))
+	require.NoError(t, j.Append(&journal.Entry{ArtifactsAdded: &journal.ArtifactsAdded{WorkerID: "w1", Artifacts: []build.ID{{'a'}}}}))
+	require.NoError(t, j.Append(&journal.Entry{WorkerLost: &journal.WorkerLost{WorkerID: "w0"}}))
+	require.NoError(t, j.Close())
+
+	j, state, err := journal.Open(root)
+	require.NoError(t, err)
+	defer j.Close()
+
+	require.Equal(t, map[build.ID]map[api.WorkerID]struct{}{
+		{'a'}: {"w1": {}},
+	}, state.Artifacts)
+}
@@ -0,0 +1,148 @@
+package journal
+
+import (
+	"bytes"
+	"sort"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// State описывает состояние координатора, восстановленное из журнала.
+type State struct {
+	// Builds хранит незавершённые сборки.
+	Builds map[build.ID]*build.Graph
+
+	// Users хранит пользователя каждой незавершённой сборки, если он был задан.
+	Users map[build.ID]string
+
+	// Results хранит результаты джобов, которые нужны незавершённым сборкам.
+	Results map[build.ID]*api.JobResult
+
+	// Artifacts хранит множество воркеров, на которых лежит каждый артефакт.
+	Artifacts map[build.ID]map[api.WorkerID]struct{}
+}
+
+func NewState() *State {
+	return &State{
+		Builds:    make(map[build.ID]*build.Graph),
+		Users:     make(map[build.ID]string),
+		Results:   make(map[build.ID]*api.JobResult),
+		Artifacts: make(map[build.ID]map[api.WorkerID]struct{}),
+	}
+}
+
+// Apply применяет запись журнала к состоянию.
+func (s *State) Apply(e *Entry) {
+	switch {
+	case e.BuildStarted != nil:
+		graph := e.BuildStarted.Graph
+		s.Builds[e.BuildStarted.ID] = &graph
+		if e.BuildStarted.User != "" {
+			s.Users[e.BuildStarted.ID] = e.BuildStarted.User
+		}
+
+	case e.JobFinished != nil:
+		res := e.JobFinished.Result
+		s.Results[res.ID] = &res
+
+		// Упавший джоб не оставляет артефакта на воркере.
+		if e.JobFinished.WorkerID != "" && res.Error == nil {
+			s.addArtifact(res.ID, e.JobFinished.WorkerID)
+		}
+
+	case e.ArtifactsAdded != nil:
+		for _, id := range e.ArtifactsAdded.Artifacts {
+			s.addArtifact(id, e.ArtifactsAdded.WorkerID)
+		}
+
+	case e.ArtifactsRemoved != nil:
+		for _, id := range e.ArtifactsRemoved.Artifacts {
+			delete(s.Artifacts[id], e.ArtifactsRemoved.WorkerID)
+			if len(s.Artifacts[id]) == 0 {
+				delete(s.Artifacts, id)
+			}
+		}
+
+	case e.WorkerLost != nil:
+		for id, workers := range s.Artifacts {
+			delete(workers, e.WorkerLost.WorkerID)
+			if len(workers) == 0 {
+				delete(s.Artifacts, id)
+			}
+		}
+
+	case e.BuildFinished != nil:
+		delete(s.Builds, e.BuildFinished.ID)
+		delete(s.Users, e.BuildFinished.ID)
+	}
+}
+
+func (s *State) addArtifact(id build.ID, workerID api.WorkerID) {
+	workers, ok := s.Artifacts[id]
+	if !ok {
+		workers = make(map[api.WorkerID]struct{})
+		s.Artifacts[id] = workers
+	}
+	workers[workerID] = struct{}{}
+}
+
+// Snapshot возвращает минимальный набор записей, из которого Apply восстановит текущее состояние.
+//
+// Результаты джобов, которые не нужны ни одной незавершённой сборке, в снапшот не попадают.
+func (s *State) Snapshot() []*Entry {
+	var entries []*Entry
+
+	needed := make(map[build.ID]struct{})
+	for _, id := range sortedIDs(s.Builds) {
+		graph := s.Builds[id]
+		for _, job := range graph.Jobs {
+			needed[job.ID] = struct{}{}
+		}
+
+		entries = append(entries, &Entry{BuildStarted: &BuildStarted{ID: id, Graph: *graph, User: s.Users[id]}})
+	}
+
+	byWorker := make(map[api.WorkerID][]build.ID)
+	for _, id := range sortedIDs(s.Artifacts) {
+		for workerID := range s.Artifacts[id] {
+			byWorker[workerID] = append(byWorker[workerID], id)
+		}
+	}
+
+	var workers []api.WorkerID
+	for workerID := range byWorker {
+		workers = append(workers, workerID)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i] < workers[j] })
+
+	for _, workerID := range workers {
+		entries = append(entries, &Entry{ArtifactsAdded: &ArtifactsAdded{
+			WorkerID:  workerID,
+			Artifacts: byWorker[workerID],
+		}})
+	}
+
+	for _, id := range sortedIDs(s.Results) {
+		if _, ok := needed[id]; !ok {
+			continue
+		}
+
+		// Местоположение артефакта уже записано в ArtifactsAdded выше.
+		entries = append(entries, &Entry{JobFinished: &JobFinished{Result: *s.Results[id]}})
+	}
+
+	return entries
+}
+
+func sortedIDs[V any](m map[build.ID]V) []build.ID {
+	var ids []build.ID
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return ids
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path задаёт путь, по которому координатор и воркер отдают метрики.
+const Path = "/metrics"
+
+// Очереди шедулера, которые различает метка queue.
+const (
+	QueueGlobal = "global"
+	QueueLocal1 = "local1"
+	QueueLocal2 = "local2"
+)
+
+// Scheduler содержит метрики шедулера.
+type Scheduler struct {
+	// PendingJobs показывает, сколько джобов ждут в каждой из очередей.
+	//
+	// Для локальных очередей значение суммируется по всем воркерам.
+	PendingJobs *prometheus.GaugeVec
+
+	// PickedJobs считает джобы, которые воркеры забрали из каждой из очередей.
+	PickedJobs *prometheus.CounterVec
+
+	// SpeculativeJobs считает копии отстающих джобов, запущенные на других воркерах.
+	SpeculativeJobs prometheus.Counter
+}
+
+// Coordinator содержит метрики координатора.
+type Coordinator struct {
+	Scheduler *Scheduler
+
+	// HeartbeatDuration измеряет время обработки heartbeat.
+	HeartbeatDuration prometheus.Histogram
+
+	// FreeSlots показывает последнее значение HeartbeatRequest.FreeSlots каждого воркера.
+	//
+	// Серию потерянного воркера нужно удалить через ForgetWorker, иначе она навсегда останется
+	// с последним значением.
+	FreeSlots *prometheus.GaugeVec
+
+	// JobDuration измеряет время от ScheduleJob до получения результата джоба.
+	JobDuration *prometheus.HistogramVec
+
+	// CachedJobs считает джобы, результат которых нашёлся в кеше без запуска.
+	CachedJobs prometheus.Counter
+}
+
+// Worker содержит метрики воркера.
+type Worker struct {
+	// HeartbeatDuration измеряет время heartbeat-запроса к координатору.
+	HeartbeatDuration prometheus.Histogram
+
+	// FreeSlots показывает, сколько ещё джобов воркер может запустить.
+	FreeSlots prometheus.Gauge
+
+	// JobDuration измеряет время выполнения джобов на воркере.
+	JobDuration *prometheus.HistogramVec
+
+	// ArtifactCacheHits и ArtifactCacheMisses считают артефакты зависимостей, которые
+	// нашлись или не нашлись в локальном кеше.
+	ArtifactCacheHits   prometheus.Counter
+	ArtifactCacheMisses prometheus.Counter
+
+	// ArtifactDownloadBytes считает размер скачанных артефактов.
+	ArtifactDownloadBytes prometheus.Counter
+
+	// ArtifactDownloadDuration измеряет время скачивания одного артефакта.
+	ArtifactDownloadDuration prometheus.Histogram
+}
+
+func newJobDuration(subsystem string) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "distbuild",
+		Subsystem: subsystem,
+		Name:      "job_duration_seconds",
+		Help:      "Job duration by exit code.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{"exit_code"})
+}
+
+func newHeartbeatDuration(subsystem string) prometheus.Histogram {
+	return prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "distbuild",
+		Subsystem: subsystem,
+		Name:      "heartbeat_duration_seconds",
+		Help:      "Heartbeat latency.",
+		Buckets:   prometheus.DefBuckets,
+	})
+}
+
+// NewScheduler создаёт метрики шедулера и регистрирует их в r.
+func NewScheduler(r prometheus.Registerer) *Scheduler {
+	m := &Scheduler{
+		PendingJobs: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "distbuild",
+			Subsystem: "scheduler",
+			Name:      "pending_jobs",
+			Help:      "Number of jobs waiting in scheduler queues.",
+		}, []string{"queue"}),
+		PickedJobs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "scheduler",
+			Name:      "picked_jobs_total",
+			Help:      "Number of jobs picked by workers from scheduler queues.",
+		}, []string{"queue"}),
+		SpeculativeJobs: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "scheduler",
+			Name:      "speculative_jobs_total",
+			Help:      "Number of duplicate copies started for straggler jobs.",
+		}),
+	}
+
+	for _, queue := range []string{QueueGlobal, QueueLocal1, QueueLocal2} {
+		m.PendingJobs.WithLabelValues(queue)
+		m.PickedJobs.WithLabelValues(queue)
+	}
+
+	r.MustRegister(m.PendingJobs, m.PickedJobs, m.SpeculativeJobs)
+	return m
+}
+
+// NewCoordinator создаёт метрики координатора вместе с метриками шедулера и регистрирует их в r.
+func NewCoordinator(r prometheus.Registerer) *Coordinator {
+	m := &Coordinator{
+		Scheduler:         NewScheduler(r),
+		HeartbeatDuration: newHeartbeatDuration("coordinator"),
+		FreeSlots: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "distbuild",
+			Subsystem: "coordinator",
+			Name:      "worker_free_slots",
+			Help:      "Free slots reported by each worker.",
+		}, []string{"worker"}),
+		JobDuration: newJobDuration("coordinator"),
+		CachedJobs: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "coordinator",
+			Name:      "cached_jobs_total",
+			Help:      "Number of jobs whose results were found in cache.",
+		}),
+	}
+
+	r.MustRegister(m.HeartbeatDuration, m.FreeSlots, m.JobDuration, m.CachedJobs)
+	return m
+}
+
+// ForgetWorker удаляет серии воркера, которого координатор посчитал мёртвым.
+//
+// Если воркер вернётся, серии появятся снова со следующим heartbeat-ом.
+func (m *Coordinator) ForgetWorker(worker string) {
+	m.FreeSlots.DeleteLabelValues(worker)
+}
+
+// NewWorker создаёт метрики воркера и регистрирует их в r.
+func NewWorker(r prometheus.Registerer) *Worker {
+	m := &Worker{
+		HeartbeatDuration: newHeartbeatDuration("worker"),
+		FreeSlots: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "free_slots",
+			Help:      "Number of jobs the worker can start right now.",
+		}),
+		JobDuration: newJobDuration("worker"),
+		ArtifactCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_cache_hits_total",
+			Help:      "Number of dependency artifacts found in the local cache.",
+		}),
+		ArtifactCacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_cache_misses_total",
+			Help:      "Number of dependency artifacts downloaded from other workers.",
+		}),
+		ArtifactDownloadBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_download_bytes_total",
+			Help:      "Size of downloaded artifacts.",
+		}),
+		ArtifactDownloadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_download_duration_seconds",
+			Help:      "Artifact download latency.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	r.MustRegister(
+		m.HeartbeatDuration,
+		m.FreeSlots,
+		m.JobDuration,
+		m.ArtifactCacheHits,
+		m.ArtifactCacheMisses,
+		m.ArtifactDownloadBytes,
+		m.ArtifactDownloadDuration,
+	)
+	return m
+}
+
+// ObserveJob записывает длительность джоба, начавшегося в start и завершившегося с кодом exitCode.
+func ObserveJob(h *prometheus.HistogramVec, start time.Time, exitCode int) {
+	h.WithLabelValues(strconv.Itoa(exitCode)).Observe(time.Since(start).Seconds())
+}
+
+// Handler возвращает http.Handler, отдающий метрики из g.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
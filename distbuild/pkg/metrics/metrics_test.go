@@ -0,0 +1,62 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+)
+
+func TestCoordinatorMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewCoordinator(reg)
+
+	m.Scheduler.PendingJobs.WithLabelValues(metrics.QueueGlobal).Set(3)
+	m.Scheduler.PickedJobs.WithLabelValues(metrics.QueueLocal1).Inc()
+	m.FreeSlots.WithLabelValues("worker0").Set(2)
+	metrics.ObserveJob(m.JobDuration, time.Now(), 1)
+
+	expected := `
+# HELP distbuild_scheduler_pending_jobs Number of jobs waiting in scheduler queues.
+# TYPE distbuild_scheduler_pending_jobs gauge
+distbuild_scheduler_pending_jobs{queue="global"} 3
+distbuild_scheduler_pending_jobs{queue="local1"} 0
+distbuild_scheduler_pending_jobs{queue="local2"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "distbuild_scheduler_pending_jobs"))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.Scheduler.PickedJobs.WithLabelValues(metrics.QueueLocal1)))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.FreeSlots.WithLabelValues("worker0")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.JobDuration, "distbuild_coordinator_job_duration_seconds"))
+
+	m.ForgetWorker("worker0")
+	require.Zero(t, testutil.CollectAndCount(m.FreeSlots))
+}
+
+func TestHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWorker(reg)
+	m.ArtifactDownloadBytes.Add(1024)
+
+	// Каждый воркер регистрирует метрики в своём реестре, поэтому в одном процессе их может быть несколько.
+	metrics.NewWorker(prometheus.NewRegistry())
+
+	server := httptest.NewServer(metrics.Handler(reg))
+	defer server.Close()
+
+	rsp, err := http.Get(server.URL + metrics.Path)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "distbuild_worker_artifact_download_bytes_total 1024")
+}
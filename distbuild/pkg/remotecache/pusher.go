@@ -0,0 +1,115 @@
+package remotecache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// UploadFunc заливает один артефакт в удалённый кеш.
+type UploadFunc func(ctx context.Context, id build.ID) error
+
+// ArtifactUploader возвращает UploadFunc, заливающую артефакты из c в удалённый кеш по адресу endpoint.
+func ArtifactUploader(endpoint string, c *artifact.Cache) UploadFunc {
+	return func(ctx context.Context, id build.ID) error {
+		return artifact.Upload(ctx, endpoint, c, id)
+	}
+}
+
+// ArtifactUploaderWithHTTP работает так же, как ArtifactUploader, но заливает артефакты через httpClient.
+//
+// Так воркер предъявляет свой сертификат удалённому кешу, созданному через NewServerWithAuth.
+func ArtifactUploaderWithHTTP(httpClient *http.Client, endpoint string, c *artifact.Cache) UploadFunc {
+	return func(ctx context.Context, id build.ID) error {
+		return artifact.UploadWithHTTP(ctx, httpClient, endpoint, c, id)
+	}
+}
+
+// Pusher асинхронно заливает артефакты воркера в удалённый кеш.
+//
+// Заливка не должна задерживать выполнение джобов, поэтому Push никогда не блокируется.
+// Если очередь переполнена, артефакт просто не попадёт в удалённый кеш.
+type Pusher struct {
+	l          *zap.Logger
+	upload     UploadFunc
+	retryDelay time.Duration
+
+	queue chan build.ID
+
+	mu      sync.Mutex
+	pending map[build.ID]struct{}
+}
+
+func NewPusher(l *zap.Logger, upload UploadFunc, queueSize int) *Pusher {
+	return &Pusher{
+		l:          l,
+		upload:     upload,
+		retryDelay: 100 * time.Millisecond,
+		queue:      make(chan build.ID, queueSize),
+		pending:    make(map[build.ID]struct{}),
+	}
+}
+
+// Push ставит артефакт в очередь на заливку.
+//
+// Push возвращает false, если артефакт не удалось поставить в очередь.
+func (p *Pusher) Push(id build.ID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pending[id]; ok {
+		return true
+	}
+
+	select {
+	case p.queue <- id:
+		p.pending[id] = struct{}{}
+		return true
+	default:
+		p.l.Warn("remote cache queue is full, dropping artifact", zap.Stringer("artifact_id", id))
+		return false
+	}
+}
+
+// Run заливает артефакты из очереди, пока не отменят ctx.
+//
+// Неудачная заливка повторяется один раз. Артефакт, который так и не удалось залить,
+// выбрасывается из очереди: его всегда можно пересобрать.
+func (p *Pusher) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case id := <-p.queue:
+			err := p.upload(ctx, id)
+			if err != nil && ctx.Err() == nil {
+				p.l.Warn("artifact upload failed, retrying", zap.Stringer("artifact_id", id), zap.Error(err))
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(p.retryDelay):
+				}
+
+				err = p.upload(ctx, id)
+			}
+
+			if err != nil {
+				p.l.Error("artifact upload failed", zap.Stringer("artifact_id", id), zap.Error(err))
+			} else {
+				p.l.Debug("artifact uploaded to remote cache", zap.Stringer("artifact_id", id))
+			}
+
+			p.mu.Lock()
+			delete(p.pending, id)
+			p.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package remotecache_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+type attempt struct {
+	id build.ID
+	ok bool
+}
+
+type fakeRemote struct {
+	mu       sync.Mutex
+	failures map[build.ID]int
+	attempts chan attempt
+	block    chan struct{}
+}
+
+func newFakeRemote() *fakeRemote {
+	return &fakeRemote{
+		failures: make(map[build.ID]int),
+		attempts: make(chan attempt, 16),
+	}
+}
+
+func (f *fakeRemote) upload(ctx context.Context, id build.ID) error {
+	if f.block != nil {
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures[id] > 0 {
+		f.failures[id]--
+		f.attempts <- attempt{id: id}
+		return fmt.Errorf("upload failed")
+	}
+
+	f.attempts <- attempt{id: id, ok: true}
+	return nil
+}
+
+func runPusher(t *testing.T, p *remotecache.Pusher) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- p.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-stopped, context.Canceled)
+	})
+}
+
+func expectAttempt(t *testing.T, f *fakeRemote, expected attempt) {
+	select {
+	case got := <-f.attempts:
+		require.Equal(t, expected, got)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("artifact %s was not uploaded", expected.id)
+	}
+}
+
+func TestPusherUpload(t *testing.T) {
+	f := newFakeRemote()
+	f.failures[build.ID{'b'}] = 1
+	f.failures[build.ID{'c'}] = 2
+
+	p := remotecache.NewPusher(zaptest.NewLogger(t), f.upload, 10)
+	runPusher(t, p)
+
+	require.True(t, p.Push(build.ID{'a'}))
+	expectAttempt(t, f, attempt{id: build.ID{'a'}, ok: true})
+
+	require.True(t, p.Push(build.ID{'b'}))
+	expectAttempt(t, f, attempt{id: build.ID{'b'}})
+	expectAttempt(t, f, attempt{id: build.ID{'b'}, ok: true})
+
+	// После второй неудачной попытки артефакт выбрасывается из очереди.
+	require.True(t, p.Push(build.ID{'c'}))
+	expectAttempt(t, f, attempt{id: build.ID{'c'}})
+	expectAttempt(t, f, attempt{id: build.ID{'c'}})
+
+	require.True(t, p.Push(build.ID{'d'}))
+	expectAttempt(t, f, attempt{id: build.ID{'d'}, ok: true})
+}
+
+func TestPusherQueueFull(t *testing.T) {
+	f := newFakeRemote()
+	f.block = make(chan struct{})
+
+	p := remotecache.NewPusher(zaptest.NewLogger(t), f.upload, 1)
+
+	require.True(t, p.Push(build.ID{'a'}))
+	require.True(t, p.Push(build.ID{'a'}))
+	require.False(t, p.Push(build.ID{'b'}))
+
+	runPusher(t, p)
+	close(f.block)
+	expectAttempt(t, f, attempt{id: build.ID{'a'}, ok: true})
+}
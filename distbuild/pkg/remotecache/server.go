@@ -0,0 +1,58 @@
+package remotecache
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// Server реализует удалённый кеш артефактов.
+//
+// Server говорит на том же протоколе, что и воркеры, поэтому скачивать из него артефакты можно
+// обычной функцией artifact.Download. Заливка артефактов идёт через artifact.Upload.
+type Server struct {
+	handler http.Handler
+}
+
+func NewServer(l *zap.Logger, cache *artifact.Cache) *Server {
+	mux := http.NewServeMux()
+	artifact.NewHandler(l, cache).Register(mux)
+
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return &Server{handler: mux}
+}
+
+// NewServerWithAuth работает так же, как NewServer, но пропускает все запросы через
+// Authenticator.Middleware.
+//
+// Заливать артефакты через PUT можно только с сертификатом, подписанным CA, то есть воркерам. Клиенту
+// с токеном доступно только чтение.
+func NewServerWithAuth(l *zap.Logger, cache *artifact.Cache, authenticator *auth.Authenticator) *Server {
+	s := NewServer(l, cache)
+	if !authenticator.Enabled() {
+		return s
+	}
+
+	next := s.handler
+	s.handler = authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if id := auth.FromContext(r.Context()); id == nil || id.Certificate == nil {
+				http.Error(w, auth.ErrNotWorker.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// FairQueue делит очередь джобов между пользователями по алгоритму weighted round robin.
+//
+// Каждый пользователь получает свою JobQueue. Pop обходит пользователей по кругу и забирает у каждого
+// подряд не больше weight джобов. Пользователь, у которого уже выполняется maxRunning джобов,
+// пропускается, пока не завершится один из его джобов.
+type FairQueue struct {
+	maxRunning int
+	weights    map[string]int
+
+	users map[string]*userQueue
+	order []string
+	next  int
+
+	owner map[*PendingJob]string
+
+	// popped хранит джобы, которые отдал Pop и для которых ещё не вызвали Done.
+	popped map[*PendingJob]struct{}
+}
+
+type userQueue struct {
+	queue   *JobQueue
+	credit  int
+	running int
+}
+
+// NewFairQueue создаёт очередь. Нулевой maxRunning означает, что ограничения нет.
+// Пользователи, которых нет в weights, получают вес 1.
+func NewFairQueue(maxRunning int, weights map[string]int) *FairQueue {
+	return &FairQueue{
+		maxRunning: maxRunning,
+		weights:    weights,
+		users:      make(map[string]*userQueue),
+		owner:      make(map[*PendingJob]string),
+		popped:     make(map[*PendingJob]struct{}),
+	}
+}
+
+func (q *FairQueue) weight(user string) int {
+	if w := q.weights[user]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (q *FairQueue) user(user string) *userQueue {
+	u, ok := q.users[user]
+	if !ok {
+		u = &userQueue{queue: NewJobQueue()}
+		q.users[user] = u
+		q.order = append(q.order, user)
+	}
+	return u
+}
+
+// Push добавляет джоб пользователя user в очередь.
+//
+// Если джоб уже в очереди, он остаётся в очереди того пользователя, который добавил его первым.
+// Джоб, который вернули в очередь после Pop, например, после потери воркера, перестаёт считаться выполняющимся.
+func (q *FairQueue) Push(user string, job *PendingJob, priority time.Duration) {
+	if owner, ok := q.owner[job]; ok {
+		user = owner
+	}
+
+	q.owner[job] = user
+	u := q.user(user)
+	if _, ok := q.popped[job]; ok {
+		delete(q.popped, job)
+		u.running--
+	}
+	u.queue.Push(job, priority)
+}
+
+// Pop извлекает следующий джоб. Если все джобы принадлежат пользователям, упёршимся в ограничение,
+// или очередь пуста, Pop возвращает nil.
+//
+// Джоб, извлечённый через Pop, считается выполняющимся, пока для него не вызовут Done.
+func (q *FairQueue) Pop() *PendingJob {
+	return q.PopFunc(nil)
+}
+
+// PopFunc работает так же, как Pop, но извлекает только джобы, чьи build.Job.Requires подходят под match,
+// см. JobQueue.PopFunc.
+//
+// Пользователь, у которого нет подходящих джобов, пропускает свою очередь так же, как пользователь
+// с пустой очередью.
+func (q *FairQueue) PopFunc(match func(requires build.Labels) bool) *PendingJob {
+	for range len(q.order) {
+		name := q.order[q.next]
+		u := q.users[name]
+
+		var job *PendingJob
+		if q.maxRunning == 0 || u.running < q.maxRunning {
+			job = u.queue.PopFunc(match)
+		}
+
+		if job == nil {
+			u.credit = 0
+			q.advance()
+			continue
+		}
+
+		if u.credit == 0 {
+			u.credit = q.weight(name)
+		}
+
+		u.credit--
+		if u.credit == 0 {
+			q.advance()
+		}
+
+		u.running++
+		q.popped[job] = struct{}{}
+		return job
+	}
+
+	return nil
+}
+
+func (q *FairQueue) advance() {
+	q.next = (q.next + 1) % len(q.order)
+}
+
+// Remove удаляет джоб из очереди, не считая его выполняющимся.
+func (q *FairQueue) Remove(job *PendingJob) bool {
+	user, ok := q.owner[job]
+	if !ok || !q.users[user].queue.Remove(job) {
+		return false
+	}
+
+	if _, running := q.popped[job]; !running {
+		delete(q.owner, job)
+	}
+	q.cleanup(user)
+	return true
+}
+
+// Done сообщает, что джоб, полученный из Pop, завершился.
+//
+// Для джоба, который ещё ждёт в очереди, Done ничего не делает.
+func (q *FairQueue) Done(job *PendingJob) {
+	if _, ok := q.popped[job]; !ok {
+		return
+	}
+	delete(q.popped, job)
+
+	user := q.owner[job]
+	u := q.users[user]
+	u.running--
+
+	if _, queued := u.queue.index[job]; !queued {
+		delete(q.owner, job)
+	}
+	q.cleanup(user)
+}
+
+// Running возвращает, сколько джобов пользователя сейчас выполняется.
+func (q *FairQueue) Running(user string) int {
+	if u, ok := q.users[user]; ok {
+		return u.running
+	}
+	return 0
+}
+
+func (q *FairQueue) Len() int {
+	n := 0
+	for _, u := range q.users {
+		n += u.queue.Len()
+	}
+	return n
+}
+
+// cleanup забывает пользователя, у которого не осталось ни ожидающих, ни выполняющихся джобов.
+func (q *FairQueue) cleanup(user string) {
+	u := q.users[user]
+	if u.queue.Len() != 0 || u.running != 0 {
+		return
+	}
+
+	delete(q.users, user)
+
+	for i, name := range q.order {
+		if name != user {
+			continue
+		}
+
+		q.order = append(q.order[:i], q.order[i+1:]...)
+		if i < q.next {
+			q.next--
+		}
+		if q.next >= len(q.order) {
+			q.next = 0
+		}
+		break
+	}
+}
@@ -0,0 +1,113 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+func pushJobs(q *scheduler.FairQueue, user string, n int) map[*scheduler.PendingJob]string {
+	owners := map[*scheduler.PendingJob]string{}
+	for range n {
+		job := &scheduler.PendingJob{}
+		q.Push(user, job, 0)
+		owners[job] = user
+	}
+	return owners
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	q := scheduler.NewFairQueue(0, map[string]int{"bob": 2})
+
+	owners := pushJobs(q, "alice", 6)
+	for job, user := range pushJobs(q, "bob", 6) {
+		owners[job] = user
+	}
+
+	var order []string
+	for range 6 {
+		order = append(order, owners[q.Pop()])
+	}
+
+	require.Equal(t, []string{"alice", "bob", "bob", "alice", "bob", "bob"}, order)
+	require.Equal(t, 6, q.Len())
+}
+
+func TestFairQueueLimit(t *testing.T) {
+	q := scheduler.NewFairQueue(2, nil)
+	pushJobs(q, "alice", 3)
+
+	first, second := q.Pop(), q.Pop()
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	require.Nil(t, q.Pop())
+	require.Equal(t, 2, q.Running("alice"))
+
+	bob := &scheduler.PendingJob{}
+	q.Push("bob", bob, 0)
+	require.Same(t, bob, q.Pop())
+
+	q.Done(first)
+	require.NotNil(t, q.Pop())
+	require.Zero(t, q.Len())
+}
+
+func TestFairQueueRemove(t *testing.T) {
+	q := scheduler.NewFairQueue(0, nil)
+
+	job := &scheduler.PendingJob{}
+	q.Push("alice", job, 0)
+	q.Push("bob", job, 0)
+
+	require.True(t, q.Remove(job))
+	require.False(t, q.Remove(job))
+	require.Nil(t, q.Pop())
+	require.Zero(t, q.Running("alice"))
+}
+
+func TestFairQueueDoneQueued(t *testing.T) {
+	q := scheduler.NewFairQueue(1, nil)
+
+	running, queued := &scheduler.PendingJob{}, &scheduler.PendingJob{}
+	q.Push("alice", running, 0)
+	q.Push("alice", queued, 0)
+
+	require.Same(t, running, q.Pop())
+	require.Equal(t, 1, q.Running("alice"))
+
+	q.Done(queued)
+	require.Equal(t, 1, q.Running("alice"))
+	require.Nil(t, q.Pop())
+
+	q.Done(running)
+	q.Done(running)
+	require.Zero(t, q.Running("alice"))
+	require.Same(t, queued, q.Pop())
+	require.Equal(t, 1, q.Running("alice"))
+}
+
+func TestFairQueueRequeue(t *testing.T) {
+	q := scheduler.NewFairQueue(1, nil)
+
+	job := &scheduler.PendingJob{}
+	q.Push("alice", job, 0)
+
+	require.Same(t, job, q.Pop())
+	require.Equal(t, 1, q.Running("alice"))
+
+	q.Push("alice", job, 0)
+	require.Zero(t, q.Running("alice"))
+
+	require.Same(t, job, q.Pop())
+	require.Equal(t, 1, q.Running("alice"))
+
+	q.Done(job)
+	require.Zero(t, q.Running("alice"))
+	require.Zero(t, q.Len())
+
+	next := &scheduler.PendingJob{}
+	q.Push("alice", next, 0)
+	require.Same(t, next, q.Pop())
+}
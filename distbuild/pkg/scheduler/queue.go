@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"container/heap"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// JobQueue хранит ожидающие джобы и отдаёт первым джоб с наибольшим приоритетом.
+//
+// Джобы с одинаковым приоритетом отдаются в порядке добавления. Внутри JobQueue держит отдельную кучу
+// для каждого набора build.Job.Requires, поэтому PopFunc проверяет условие один раз на набор меток,
+// а не на каждый джоб.
+type JobQueue struct {
+	groups map[string]*jobGroup
+	index  map[*PendingJob]*queueItem
+	seq    uint64
+}
+
+// jobGroup хранит джобы с одинаковыми build.Job.Requires.
+type jobGroup struct {
+	key      string
+	requires build.Labels
+	items    jobHeap
+}
+
+type queueItem struct {
+	job      *PendingJob
+	group    *jobGroup
+	priority time.Duration
+	seq      uint64
+	pos      int
+}
+
+func (a *queueItem) before(b *queueItem) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+type jobHeap []*queueItem
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *jobHeap) Push(x any) {
+	item := x.(*queueItem)
+	item.pos = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+func NewJobQueue() *JobQueue {
+	return &JobQueue{
+		groups: make(map[string]*jobGroup),
+		index:  make(map[*PendingJob]*queueItem),
+	}
+}
+
+func (q *JobQueue) Len() int {
+	return len(q.index)
+}
+
+func jobRequires(job *PendingJob) build.Labels {
+	if job.Job == nil {
+		return nil
+	}
+	return job.Job.Requires
+}
+
+// requiresKey однозначно кодирует набор меток.
+//
+// Labels.String для этого не подходит: {"a": "b,c=d"} и {"a": "b", "c": "d"} превращаются в одну строку.
+func requiresKey(requires build.Labels) string {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(requires)) {
+		for _, s := range []string{key, requires[key]} {
+			b.WriteString(strconv.Itoa(len(s)))
+			b.WriteByte(':')
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// Push добавляет джоб в очередь. Если джоб уже в очереди, Push повышает ему приоритет, но не понижает.
+func (q *JobQueue) Push(job *PendingJob, priority time.Duration) {
+	if item, ok := q.index[job]; ok {
+		if priority > item.priority {
+			item.priority = priority
+			heap.Fix(&item.group.items, item.pos)
+		}
+		return
+	}
+
+	requires := jobRequires(job)
+	key := requiresKey(requires)
+
+	g, ok := q.groups[key]
+	if !ok {
+		g = &jobGroup{key: key, requires: requires}
+		q.groups[key] = g
+	}
+
+	q.seq++
+	item := &queueItem{job: job, group: g, priority: priority, seq: q.seq}
+	q.index[job] = item
+	heap.Push(&g.items, item)
+}
+
+// Pop извлекает джоб с наибольшим приоритетом. Если очередь пуста, Pop возвращает nil.
+func (q *JobQueue) Pop() *PendingJob {
+	return q.PopFunc(nil)
+}
+
+// PopFunc извлекает джоб с наибольшим приоритетом среди тех, чьи build.Job.Requires подходят под match.
+// Если подходящего джоба нет, PopFunc возвращает nil. Нулевой match подходит любому джобу.
+//
+// Так воркер забирает из общей очереди только те джобы, которые он может выполнить, например, передав
+// в match свой build.Labels.Satisfies. Остальные джобы остаются в очереди в прежнем порядке.
+// match вызывается один раз на каждый различный набор Requires.
+func (q *JobQueue) PopFunc(match func(requires build.Labels) bool) *PendingJob {
+	var best *queueItem
+	for _, g := range q.groups {
+		if match != nil && !match(g.requires) {
+			continue
+		}
+
+		if head := g.items[0]; best == nil || head.before(best) {
+			best = head
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	q.remove(best)
+	return best.job
+}
+
+// Remove удаляет джоб из очереди, например, когда его уже забрали из другой очереди.
+func (q *JobQueue) Remove(job *PendingJob) bool {
+	item, ok := q.index[job]
+	if !ok {
+		return false
+	}
+
+	q.remove(item)
+	return true
+}
+
+func (q *JobQueue) remove(item *queueItem) {
+	g := item.group
+	heap.Remove(&g.items, item.pos)
+	if len(g.items) == 0 {
+		delete(q.groups, g.key)
+	}
+	delete(q.index, item.job)
+}
@@ -0,0 +1,87 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+func TestJobQueue(t *testing.T) {
+	q := scheduler.NewJobQueue()
+	require.Nil(t, q.Pop())
+
+	leaf0, leaf1, chain := &scheduler.PendingJob{}, &scheduler.PendingJob{}, &scheduler.PendingJob{}
+	removed := &scheduler.PendingJob{}
+
+	q.Push(leaf0, 1)
+	q.Push(leaf1, 1)
+	q.Push(removed, 100)
+	q.Push(chain, 5)
+	q.Push(chain, 2)
+
+	require.True(t, q.Remove(removed))
+	require.False(t, q.Remove(removed))
+	require.Equal(t, 3, q.Len())
+
+	require.Same(t, chain, q.Pop())
+	require.Same(t, leaf0, q.Pop())
+
+	q.Push(leaf0, 10)
+	require.Same(t, leaf0, q.Pop())
+	require.Same(t, leaf1, q.Pop())
+	require.Nil(t, q.Pop())
+}
+
+func TestJobQueuePopFunc(t *testing.T) {
+	q := scheduler.NewJobQueue()
+
+	newJob := func(requires build.Labels) *scheduler.PendingJob {
+		return &scheduler.PendingJob{Job: &api.JobSpec{Job: build.Job{Requires: requires}}}
+	}
+
+	arm0 := newJob(build.Labels{build.LabelArch: "arm64"})
+	arm1 := newJob(build.Labels{build.LabelArch: "arm64"})
+	amd := newJob(build.Labels{build.LabelArch: "amd64"})
+	anywhere := newJob(nil)
+
+	q.Push(arm0, 10)
+	q.Push(amd, 5)
+	q.Push(anywhere, 1)
+	q.Push(arm1, 10)
+
+	calls := 0
+	worker := build.Labels{build.LabelOS: "linux", build.LabelArch: "amd64"}
+	match := func(requires build.Labels) bool {
+		calls++
+		return worker.Satisfies(requires)
+	}
+
+	require.Same(t, amd, q.PopFunc(match))
+	require.Equal(t, 3, calls)
+	require.Same(t, anywhere, q.PopFunc(match))
+	require.Nil(t, q.PopFunc(match))
+
+	require.Equal(t, 2, q.Len())
+	require.Same(t, arm0, q.Pop())
+	require.Same(t, arm1, q.Pop())
+	require.Nil(t, q.Pop())
+}
+
+func TestJobQueuePopFuncAmbiguousLabels(t *testing.T) {
+	q := scheduler.NewJobQueue()
+
+	joined := &scheduler.PendingJob{Job: &api.JobSpec{Job: build.Job{Requires: build.Labels{"a": "b,c=d"}}}}
+	split := &scheduler.PendingJob{Job: &api.JobSpec{Job: build.Job{Requires: build.Labels{"a": "b", "c": "d"}}}}
+
+	q.Push(joined, 1)
+	q.Push(split, 0)
+
+	worker := build.Labels{"a": "b", "c": "d"}
+	require.Same(t, split, q.PopFunc(worker.Satisfies))
+	require.Nil(t, q.PopFunc(worker.Satisfies))
+	require.Same(t, joined, q.Pop())
+}
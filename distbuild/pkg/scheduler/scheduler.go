@@ -10,6 +10,7 @@ import (
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
 )
 
 type PendingJob struct {
@@ -21,6 +22,25 @@ type PendingJob struct {
 type Config struct {
 	CacheTimeout time.Duration
 	DepsTimeout  time.Duration
+
+	// RemoteCache задаёт адрес удалённого кеша артефактов.
+	//
+	// Если артефакта нет ни на одном воркере, LocateArtifact возвращает RemoteCache.
+	// Пустое значение означает, что удалённого кеша нет.
+	RemoteCache api.WorkerID
+
+	// Speculation задаёт, когда запускать копию отстающего джоба на другом воркере.
+	Speculation SpeculationConfig
+
+	// MaxJobsPerUser ограничивает число одновременно выполняющихся джобов одного пользователя.
+	// Нулевое значение означает, что ограничения нет.
+	MaxJobsPerUser int
+
+	// UserWeights задаёт веса пользователей в справедливом разделении очереди. По умолчанию вес равен 1.
+	UserWeights map[string]int
+
+	// Metrics задаёт метрики, которые обновляет шедулер. Если Metrics == nil, метрики не собираются.
+	Metrics *metrics.Scheduler
 }
 
 type Scheduler struct {
@@ -38,14 +58,92 @@ func (c *Scheduler) OnJobComplete(workerID api.WorkerID, jobID build.ID, res *ap
 	panic("implement me")
 }
 
+// OnArtifactRemoved сообщает шедулеру, что артефакт был удалён из кеша на воркере.
+//
+// После этого LocateArtifact не должен возвращать этот воркер для данного артефакта, а джоб
+// больше не должен попадать в первую локальную очередь воркера.
+func (c *Scheduler) OnArtifactRemoved(workerID api.WorkerID, id build.ID) {
+	panic("implement me")
+}
+
 func (c *Scheduler) ScheduleJob(job *api.JobSpec) *PendingJob {
 	panic("implement me")
 }
 
+// ScheduleJobWithPriority работает так же, как ScheduleJob, но задаёт приоритет джоба.
+//
+// Внутри каждой очереди PickJob отдаёт первым джоб с наибольшим приоритетом, а джобы с равным
+// приоритетом - в порядке добавления. ScheduleJob эквивалентен ScheduleJobWithPriority с нулевым приоритетом.
+// Если джоб уже ждёт в очередях, его приоритет может только вырасти.
+func (c *Scheduler) ScheduleJobWithPriority(job *api.JobSpec, priority time.Duration) *PendingJob {
+	panic("implement me")
+}
+
+// ScheduleUserJob работает так же, как ScheduleJobWithPriority, но относит джоб к пользователю user.
+//
+// ScheduleJobWithPriority эквивалентен ScheduleUserJob с пустым user.
+func (c *Scheduler) ScheduleUserJob(user string, job *api.JobSpec, priority time.Duration) *PendingJob {
+	panic("implement me")
+}
+
+// CancelJob сообщает шедулеру, что одна из сборок, получивших этот джоб из ScheduleJob, больше в нём не нуждается.
+//
+// Когда джоб перестаёт быть нужен всем сборкам, шедулер убирает его из очередей. Если в этот момент
+// джоб уже выполняется, CancelJob возвращает все воркеры, на которых его нужно остановить: вместе
+// со спекулятивной копией джоб может выполняться на двух воркерах.
+func (c *Scheduler) CancelJob(jobID build.ID) []api.WorkerID {
+	panic("implement me")
+}
+
+// JobWorker возвращает воркер, на котором выполняется основная копия джоба.
+//
+// Основной считается копия, которую воркер забрал через PickJob из очереди. Спекулятивная копия
+// основной не становится. После OnWorkerLost джоб возвращается в очереди, и основным становится
+// воркер, который заберёт его следующим. Если джоб сейчас не выполняется, JobWorker возвращает false.
+func (c *Scheduler) JobWorker(jobID build.ID) (api.WorkerID, bool) {
+	panic("implement me")
+}
+
+// JobsToCancel возвращает джобы, которые нужно остановить на воркере, и забывает их.
+//
+// Сюда попадают копии спекулятивно запущенных джобов, которые проиграли: результат того же джоба уже
+// пришёл с другого воркера в OnJobComplete.
+func (c *Scheduler) JobsToCancel(workerID api.WorkerID) []build.ID {
+	panic("implement me")
+}
+
+// OnWorkerLost сообщает шедулеру, что воркер перестал присылать heartbeat-ы.
+//
+// Шедулер забывает все артефакты этого воркера и удаляет его локальные очереди. Джобы, которые воркер
+// забрал через PickJob и не успел завершить, возвращаются в очереди. OnWorkerLost возвращает их список.
+func (c *Scheduler) OnWorkerLost(workerID api.WorkerID) []build.ID {
+	panic("implement me")
+}
+
+// SetWorkerLabels запоминает метки воркера из HeartbeatRequest.Labels.
+//
+// После этого PickJob отдаёт воркеру только те джобы, чьи build.Job.Requires удовлетворяются labels.
+// Воркер, для которого SetWorkerLabels ни разу не вызывали, считается воркером без меток.
+func (c *Scheduler) SetWorkerLabels(workerID api.WorkerID, labels build.Labels) {
+	panic("implement me")
+}
+
+// PickJob забирает для воркера следующий джоб.
+//
+// Джобы, чьи build.Job.Requires не удовлетворяются метками воркера, пропускаются и остаются в очередях
+// для других воркеров. Используйте JobQueue.PopFunc и FairQueue.PopFunc с build.Labels.Satisfies меток воркера.
 func (c *Scheduler) PickJob(ctx context.Context, workerID api.WorkerID) *PendingJob {
 	panic("implement me")
 }
 
+// PickJobWithQueue работает так же, как PickJob, но дополнительно возвращает очередь, из которой
+// воркер забрал джоб: metrics.QueueGlobal, metrics.QueueLocal1 или metrics.QueueLocal2.
+//
+// Координатор записывает очередь в событие events.JobPicked.
+func (c *Scheduler) PickJobWithQueue(ctx context.Context, workerID api.WorkerID) (*PendingJob, string) {
+	panic("implement me")
+}
+
 func (c *Scheduler) Stop() {
 	panic("implement me")
 }
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// SpeculationConfig задаёт, когда шедулер запускает копию долго работающего джоба.
+//
+// Джоб считается отстающим, если он выполняется дольше Multiplier * p-го перцентиля длительности
+// последних завершённых джобов, где p = Percentile. Нулевой Percentile выключает спекулятивное исполнение.
+type SpeculationConfig struct {
+	// Percentile задаёт перцентиль от 0 до 100.
+	Percentile float64
+
+	// Multiplier задаёт множитель порога. Нулевое значение означает 1.
+	Multiplier float64
+
+	// MinSamples задаёт, сколько джобов должно завершиться, прежде чем порог начнёт считаться.
+	MinSamples int
+
+	// Window задаёт, сколько последних длительностей учитывается. Нулевое значение означает 1000.
+	Window int
+}
+
+// Stragglers хранит длительности последних джобов и решает, какой из бегущих джобов отстаёт.
+type Stragglers struct {
+	config SpeculationConfig
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func NewStragglers(config SpeculationConfig) *Stragglers {
+	if config.Multiplier == 0 {
+		config.Multiplier = 1
+	}
+
+	if config.Window == 0 {
+		config.Window = 1000
+	}
+
+	return &Stragglers{config: config}
+}
+
+// Observe запоминает длительность завершившегося джоба.
+func (s *Stragglers) Observe(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) < s.config.Window {
+		s.samples = append(s.samples, d)
+		return
+	}
+
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % s.config.Window
+}
+
+// Threshold возвращает длительность, после которой джоб считается отстающим.
+//
+// Если спекулятивное исполнение выключено или завершённых джобов пока мало, Threshold возвращает false.
+func (s *Stragglers) Threshold() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.Percentile <= 0 || len(s.samples) == 0 || len(s.samples) < s.config.MinSamples {
+		return 0, false
+	}
+
+	sorted := slices.Clone(s.samples)
+	slices.Sort(sorted)
+
+	i := int(float64(len(sorted)-1) * min(s.config.Percentile, 100) / 100)
+	return time.Duration(float64(sorted[i]) * s.config.Multiplier), true
+}
+
+// IsStraggler сообщает, нужно ли запустить копию джоба, который выполняется уже running.
+func (s *Stragglers) IsStraggler(running time.Duration) bool {
+	threshold, ok := s.Threshold()
+	return ok && running > threshold
+}
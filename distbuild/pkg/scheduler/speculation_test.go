@@ -0,0 +1,51 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+func TestStragglers(t *testing.T) {
+	s := scheduler.NewStragglers(scheduler.SpeculationConfig{Percentile: 90, Multiplier: 2, MinSamples: 10})
+
+	for i := 1; i <= 9; i++ {
+		s.Observe(time.Duration(i) * time.Second)
+	}
+
+	_, ok := s.Threshold()
+	require.False(t, ok)
+	require.False(t, s.IsStraggler(time.Hour))
+
+	s.Observe(10 * time.Second)
+
+	threshold, ok := s.Threshold()
+	require.True(t, ok)
+	require.Equal(t, 18*time.Second, threshold)
+
+	require.False(t, s.IsStraggler(15*time.Second))
+	require.True(t, s.IsStraggler(20*time.Second))
+}
+
+func TestStragglersWindow(t *testing.T) {
+	s := scheduler.NewStragglers(scheduler.SpeculationConfig{Percentile: 100, Window: 3})
+
+	s.Observe(time.Hour)
+	for range 3 {
+		s.Observe(time.Second)
+	}
+
+	threshold, ok := s.Threshold()
+	require.True(t, ok)
+	require.Equal(t, time.Second, threshold)
+}
+
+func TestStragglersDisabled(t *testing.T) {
+	s := scheduler.NewStragglers(scheduler.SpeculationConfig{})
+	s.Observe(time.Second)
+
+	require.False(t, s.IsStraggler(time.Hour))
+}
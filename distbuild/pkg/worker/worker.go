@@ -10,12 +10,58 @@ import (
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
 )
 
 type Worker struct {
 }
 
+// Config задаёт необязательные настройки воркера.
+//
+// Нулевое значение Config соответствует поведению воркера, созданного через New.
+type Config struct {
+	// Slots задаёт, сколько джобов воркер может выполнять одновременно.
+	//
+	// Нулевое значение означает, что воркер выполняет джобы по одному.
+	Slots int
+
+	// Sandbox включает запуск команд джобов внутри jobexec.Sandbox.
+	//
+	// Джобу видны только SourceDir, артефакты зависимостей и OutputDir. Программа, в которой
+	// работает воркер, должна вызвать jobexec.Init в начале main.
+	Sandbox bool
+
+	// SandboxNetwork разрешает джобам внутри песочницы доступ к сети.
+	SandboxNetwork bool
+
+	// RemoteCache задаёт адрес удалённого кеша артефактов.
+	//
+	// Воркер заливает туда артефакты всех успешно завершённых джобов через remotecache.Pusher.
+	RemoteCache string
+
+	// GRPCEndpoint задаёт адрес gRPC сервера координатора в виде host:port.
+	//
+	// Если GRPCEndpoint не пустой, воркер посылает heartbeat-ы через api.GRPCHeartbeatClient.
+	// Файлы из кеша координатора по-прежнему скачиваются по HTTP с coordinatorEndpoint. Соединение
+	// открывается через api.DialGRPC и закрывается, когда Run возвращает управление.
+	GRPCEndpoint string
+
+	// Auth задаёт сертификат воркера.
+	//
+	// Все запросы к координатору и другим воркерам воркер посылает через auth.Authenticator.HTTPClient
+	// или с опциями из auth.Authenticator.GRPCDialOptions. ServeHTTP пропускает входящие запросы
+	// через auth.Authenticator.Middleware. Authenticator создаётся через auth.NewServer.
+	Auth auth.Config
+
+	// Labels задаёт дополнительные метки воркера, например, версии инструментов и произвольные теги.
+	//
+	// Воркер посылает в HeartbeatRequest.Labels метки build.HostLabels, дополненные Labels. Метки из Labels
+	// перекрывают одноимённые метки хоста.
+	Labels build.Labels
+}
+
 func New(
 	workerID api.WorkerID,
 	coordinatorEndpoint string,
@@ -26,6 +72,17 @@ func New(
 	panic("implement me")
 }
 
+func NewWithConfig(
+	workerID api.WorkerID,
+	coordinatorEndpoint string,
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	artifacts *artifact.Cache,
+	config Config,
+) *Worker {
+	panic("implement me")
+}
+
 func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }